@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
@@ -13,11 +14,26 @@ import (
 
 var conf Config
 
+// configVersion is the current format version of .autorun.toml files.
+const configVersion = 1
+
+// configMigrations upgrade .autorun.toml files written for older versions.
+// Files without a version key are version 0, which only lacks the key.
+var configMigrations = config.NewMigrations(configVersion)
+
 type Config struct {
-	Autorun     string            `toml:"autorun,omitempty"`
-	WorkDir     string            `toml:"workDir,omitempty"`
-	Isolate     bool              `toml:"isolated,omitempty"`
-	Environment map[string]string `toml:"environment,omitempty"`
+	Version     int               `toml:"version,omitempty" doc:"Format version of this file, set when autorun saves it"`
+	Autorun     string            `toml:"autorun,omitempty" validate:"required" doc:"Program to run, relative to the drive root"`
+	WorkDir     string            `toml:"workDir,omitempty" doc:"Working directory, relative to the drive root"`
+	Isolate     bool              `toml:"isolated,omitempty" doc:"Run with an isolated environment whose user directories are on the drive"`
+	Environment map[string]string `toml:"environment,omitempty" doc:"Extra environment variables; values may use {drive}, {work} and ${NAME}"`
+}
+
+// loadConfig loads an .autorun.toml file into cfg. Files of older versions
+// are migrated in memory only, as drives may be read-only or shared with
+// older installations.
+func loadConfig(path string, cfg *Config) error {
+	return config.Load(path, cfg, config.WithFormat("toml"), config.WithMigrations(configMigrations))
 }
 
 func startAutorun(drivePath string) {
@@ -32,7 +48,7 @@ func startAutorun(drivePath string) {
 	// Read the config file using pkg/config
 	configPath := drivePath + "/.autorun.toml"
 	log.Printf("[AUTORUN] Checking for config file: %s\n", configPath)
-	err := config.LoadToml(&conf, configPath)
+	err := loadConfig(configPath, &conf)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			log.Printf("[AUTORUN] Error reading config file: %s\n", err)
@@ -42,15 +58,13 @@ func startAutorun(drivePath string) {
 		return
 	}
 
-	if conf.Autorun == "" {
-		log.Printf("[AUTORUN] No autorun program specified in config\n")
-		return
-	}
-
 	log.Printf("[AUTORUN] Found autorun config: %s\n", conf.Autorun)
 
-	// Set the environment variables using pkg/config
-	conf = *setupEnvironment(&conf)
+	// Expand the config values using pkg/config
+	if err := setupEnvironment(&conf); err != nil {
+		log.Printf("[AUTORUN] Error in config file %s: %s\n", configPath, err)
+		return
+	}
 
 	if !filepath.IsAbs(conf.Autorun) {
 		conf.Autorun = filepath.Join(drivePath, conf.Autorun)
@@ -61,8 +75,6 @@ func startAutorun(drivePath string) {
 
 	// start building the command
 	cmd := exec.Command(conf.Autorun)
-	cmd.Env = []string{}
-	customEnv := map[string]string{}
 
 	isolatedEnv := map[string]string{
 		"HOME":              filepath.Join(drivePath, "/.isolated/User"),
@@ -95,18 +107,8 @@ func startAutorun(drivePath string) {
 			}
 		}
 
-		// Prepare environment for execution
-		customEnv = isolatedEnv
-		for key, value := range conf.Environment {
-			customEnv[key] = value
-		}
-
-		// Convert environment map to slice
-		envSlice := []string{}
-		for key, value := range customEnv {
-			envSlice = append(envSlice, key+"="+value)
-		}
-		cmd.Env = envSlice
+		// Prepare environment for execution, without inheriting the tray's environment
+		cmd.Env = config.EnvFromMap(isolatedEnv).With(conf.Environment).Environ()
 		cmd.Dir = conf.WorkDir
 		if cmd.Dir == "" {
 			cmd.Dir = isolatedRoot
@@ -152,22 +154,8 @@ func startAutorun(drivePath string) {
 		}
 
 	} else {
-		// Use original environment-only isolation
-		for _, env := range os.Environ() {
-			parts := strings.SplitN(env, "=", 2)
-			if len(parts) == 2 {
-				customEnv[parts[0]] = parts[1]
-			}
-		}
-
-		// Add custom environment variables
-		for key, value := range conf.Environment {
-			customEnv[key] = value
-		}
-
-		for key, value := range customEnv {
-			cmd.Env = append(cmd.Env, key+"="+value)
-		}
+		// Inherit the tray's environment and add the custom variables for this drive only
+		cmd.Env = config.Environ().With(conf.Environment).Environ()
 		cmd.Dir = conf.WorkDir
 
 		// Start the autorun program
@@ -183,28 +171,36 @@ func startAutorun(drivePath string) {
 
 }
 
-// SetupEnvironment sets up environment variables and replaces placeholders in the config.
-func setupEnvironment(conf *Config) *Config {
+// setupEnvironment expands {work}, {drive}, ${ENV} and ${ENV:-default}
+// references in the config. The environment variables are only applied to
+// the launched program, never to the tray process itself.
+func setupEnvironment(conf *Config) error {
 	debug.Print("config.SetupEnvironment called with config:", conf)
-	// Variables for env replacement
+	// Variables for env replacement; {work} may itself refer to {drive}
 	drivePath, _ := filepath.Abs("/")
 	drivePath = filepath.ToSlash(drivePath)
 	drivePath = strings.TrimSuffix(drivePath, "/")
-	configEnvReplace := map[string]string{
-		"{work}":  conf.WorkDir,
-		"{drive}": drivePath,
-	}
-	debug.Print("Config environment replacements:", configEnvReplace)
+	expander := config.NewExpander(map[string]string{
+		"work":  conf.WorkDir,
+		"drive": drivePath,
+	})
+	debug.Print("Config environment replacements:", expander.Vars)
 
 	// Replace Normal Config options
-	conf.Autorun = config.EnvKeyReplace(conf.Autorun, configEnvReplace)
-	conf.WorkDir = config.EnvKeyReplace(conf.WorkDir, configEnvReplace)
+	var err error
+	if conf.Autorun, err = expander.Expand(conf.Autorun); err != nil {
+		return fmt.Errorf("autorun: %w", err)
+	}
+	if conf.WorkDir, err = expander.Expand(conf.WorkDir); err != nil {
+		return fmt.Errorf("workDir: %w", err)
+	}
 
 	// Replace Environment Variables and set them
 	for k, v := range conf.Environment {
-		conf.Environment[k] = config.EnvKeyReplace(v, configEnvReplace)
+		if conf.Environment[k], err = expander.Expand(v); err != nil {
+			return fmt.Errorf("environment.%s: %w", k, err)
+		}
 	}
-	config.EnvOverride(conf.Environment)
 
-	return conf
+	return nil
 }
@@ -37,7 +37,7 @@ func showDriveConfigDialog(win fyne.Window, drive DriveInfo) {
 	// Load config if present
 	cfg := Config{Environment: map[string]string{}}
 	if drive.HasConfig {
-		config.LoadToml(&cfg, filepath.Join(drive.Letter, ".autorun.toml"))
+		loadConfig(filepath.Join(drive.Letter, ".autorun.toml"), &cfg)
 	}
 
 	// Create the content
@@ -228,6 +228,7 @@ func createEnvironmentRow(key, value string, envRows *[]*envRow, envContainer *f
 // saveConfig saves the configuration
 func saveConfig(drive DriveInfo, autorunEntry, workDirEntry *widget.Entry, isolateCheck *widget.Check, envRows *[]*envRow, configWin fyne.Window) {
 	cfg := Config{
+		Version:     configVersion,
 		Autorun:     autorunEntry.Text,
 		WorkDir:     workDirEntry.Text,
 		Isolate:     isolateCheck.Checked,
@@ -7,14 +7,16 @@
 //   - Security dialogs for drive access confirmation
 //   - Per-drive configuration management
 //   - Startup folder installation capability
-//   - Timeout-based self-destruct for testing
+//   - Timeout-based graceful shutdown for testing
 //
 // Usage:
 //   autorun [-install] [-timeout seconds]
 //
 // Flags:
 //   -install, -i    Install autorun service to Windows startup folder
-//   -timeout        Exit after N seconds (primarily for testing)
+//   -timeout        Shut down gracefully after N seconds (primarily for testing)
+//   -print-config-schema     Print the JSON Schema of .autorun.toml files
+//   -init-config file        Write a documented example .autorun.toml
 //
 // The application runs in the system tray and shows a window when clicked.
 // It continuously monitors for new removable drives and can execute configured
@@ -25,6 +27,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
@@ -33,12 +36,15 @@ import (
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"github.com/Merith-TK/utils/pkg/config"
+	"github.com/Merith-TK/utils/pkg/debug"
 	"github.com/getlantern/systray"
 )
 
 var (
 	install       bool
 	timeout       int // seconds, 0 means no timeout
+	schemaFlags   *config.SchemaFlags
 	startupFolder = filepath.Join(os.Getenv("appdata"), "Microsoft", "Windows", "Start Menu", "Programs", "Startup")
 )
 
@@ -46,10 +52,18 @@ func init() {
 	flag.BoolVar(&install, "install", false, "Install autorun service")
 	flag.BoolVar(&install, "i", false, "Install autorun service")
 	flag.IntVar(&timeout, "timeout", 0, "Exit after N seconds (for testing)")
+	debug.RegisterFlags(flag.CommandLine)
+	schemaFlags = config.RegisterSchemaFlags(flag.CommandLine)
 }
 
 func main() {
 	flag.Parse()
+	if done, err := schemaFlags.Handle(&Config{Version: configVersion, Autorun: "/setup.exe", WorkDir: "/"}); done {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	log.Printf("[MAIN] Flags parsed. install=%v, timeout=%v", install, timeout)
 
 	// Initialize security manager
@@ -57,11 +71,7 @@ func main() {
 	log.Printf("[MAIN] Security manager initialized")
 
 	if timeout > 0 {
-		go func() {
-			time.Sleep(time.Duration(timeout) * time.Second)
-			log.Printf("[TIMEOUT] Exiting after %d seconds (self-destruct)", timeout)
-			os.Exit(0)
-		}()
+		debug.StartWatchdog(time.Duration(timeout) * time.Second)
 	}
 	if install {
 		copyToStartupFolder()
@@ -87,6 +97,17 @@ func main() {
 		win.Hide()
 	})
 
+	// Quit the tray and the Fyne app when the watchdog shuts the process down
+	debug.OnShutdown(func(ctx context.Context) error {
+		systray.Quit()
+		select {
+		case uiActionCh <- func() { fyneApp.Quit() }:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
 	refreshContent := func() {
 		win.SetContent(buildMainContent(win, configDialogCh))
 	}
@@ -134,4 +155,7 @@ func main() {
 	startDriveMonitor(uiRefreshCh)
 
 	fyneApp.Run()
+	// Run also returns when the watchdog quits the app; Shutdown then waits
+	// for the hooks and returns ExitTimeout instead of letting main exit 0.
+	os.Exit(debug.Shutdown(debug.ExitOK))
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -53,10 +54,33 @@ type ConfigMetadata struct {
 	Environment  map[string]string `json:"environment"`
 }
 
+// metadataVersion is the current format version of the metadata file.
+const metadataVersion = 1
+
+// metadataMigrations upgrade metadata files written by older versions.
+var metadataMigrations = config.NewMigrations(metadataVersion).
+	Register(0, func(doc map[string]any) error {
+		// Version 0 was a bare map from drive serial to metadata.
+		drives := make(map[string]any, len(doc))
+		for key, value := range doc {
+			drives[key] = value
+			delete(doc, key)
+		}
+		doc["drives"] = drives
+		return nil
+	})
+
+// metadataFile is the content of the metadata file.
+type metadataFile struct {
+	Version int                        `json:"version"`
+	Drives  map[string]*ConfigMetadata `json:"drives"`
+}
+
 // SecurityManager manages security decisions for autorun configs
 type SecurityManager struct {
 	metadataPath string
 	metadata     map[string]*ConfigMetadata
+	loadErr      error // set if the metadata file exists but could not be read
 }
 
 // NewSecurityManager creates a new security manager
@@ -78,31 +102,40 @@ func NewSecurityManager() *SecurityManager {
 	return sm
 }
 
-// loadMetadata loads existing security metadata from disk
+// loadMetadata loads existing security metadata from disk, upgrading files
+// written by older versions. The stored configs are not validated, since one
+// that no longer passes must not drop the decisions for every drive; configs
+// are validated when they are loaded from a drive.
 func (sm *SecurityManager) loadMetadata() {
-	data, err := os.ReadFile(sm.metadataPath)
+	var file metadataFile
+	err := config.Load(sm.metadataPath, &file, config.WithMigrations(metadataMigrations),
+		config.WithRewrite(), config.WithoutValidation())
 	if err != nil {
-		if !os.IsNotExist(err) {
-			fmt.Printf("[SECURITY] Error reading metadata file: %v\n", err)
+		if !errors.Is(err, os.ErrNotExist) {
+			sm.loadErr = err
+			fmt.Printf("[SECURITY] Error reading metadata file, decisions will not be saved: %v\n", err)
 		}
 		return
 	}
-	
-	if err := json.Unmarshal(data, &sm.metadata); err != nil {
-		fmt.Printf("[SECURITY] Error parsing metadata file: %v\n", err)
-		return
+	if file.Drives != nil {
+		sm.metadata = file.Drives
 	}
 }
 
 // saveMetadata saves security metadata to disk
 func (sm *SecurityManager) saveMetadata() {
-	data, err := json.MarshalIndent(sm.metadata, "", "  ")
+	if sm.loadErr != nil {
+		// Saving would overwrite the decisions in the unreadable file.
+		return
+	}
+	file := metadataFile{Version: metadataVersion, Drives: sm.metadata}
+	data, err := json.MarshalIndent(file, "", "  ")
 	if err != nil {
 		fmt.Printf("[SECURITY] Error marshaling metadata: %v\n", err)
 		return
 	}
 	
-	if err := os.WriteFile(sm.metadataPath, data, 0644); err != nil {
+	if err := config.WriteFile(sm.metadataPath, data, config.WithBackup()); err != nil {
 		fmt.Printf("[SECURITY] Error writing metadata file: %v\n", err)
 	}
 }
@@ -152,7 +185,7 @@ func (sm *SecurityManager) CheckConfig(drivePath string) (SecurityDecision, *Con
 	
 	// Load the config
 	var cfg Config
-	if err := config.LoadToml(&cfg, configPath); err != nil {
+	if err := loadConfig(configPath, &cfg); err != nil {
 		return SecurityDecisionDeny, nil, fmt.Errorf("failed to load config: %v", err)
 	}
 	
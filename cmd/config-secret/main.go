@@ -0,0 +1,99 @@
+// Package main implements a tool for keeping credentials out of config files
+// in cleartext, using the secret references resolved by pkg/config.
+//
+// Config fields tagged `secret:"true"` accept:
+//   file:/path   the content of a file
+//   env:NAME     an environment variable
+//   enc:...      a value encrypted with a local key file
+//
+// Available commands:
+//   keygen            create a new key file
+//   encrypt [value]   print the enc: reference for a value
+//   decrypt [value]   print the cleartext of an enc: reference
+//
+// Values that are not given as an argument are read from standard input,
+// so they do not end up in the shell history.
+//
+// Usage:
+//   config-secret [-key key-file] command [value]
+//
+// Flags:
+//   -key    Path to the key file (default: $CONFIG_KEY_FILE, or config.key
+//           in the user config directory)
+//
+// Examples:
+//   config-secret keygen
+//   config-secret encrypt           # type the password and press Enter
+//   config-secret decrypt "enc:..."
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Merith-TK/utils/pkg/config"
+)
+
+func main() {
+	keyFile := flag.String("key", config.DefaultKeyFile(), "path to the key file")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: config-secret [-key key-file] keygen | encrypt [value] | decrypt [value]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "keygen":
+		if _, err := config.NewKeyFile(*keyFile); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintln(os.Stderr, "Created key file", *keyFile)
+	case "encrypt":
+		key, err := config.ReadKeyFile(*keyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		value, err := config.EncryptSecret(readValue(args[1:]), key)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(value)
+	case "decrypt":
+		key, err := config.ReadKeyFile(*keyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		value, err := config.DecryptSecret(readValue(args[1:]), key)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(value)
+	default:
+		log.Printf("Unknown command: %s", args[0])
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// readValue returns the value given as argument, or else the first line of
+// standard input.
+func readValue(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Fatal(err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
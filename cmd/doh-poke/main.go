@@ -61,6 +61,7 @@ type DoHResponse struct {
 }
 
 func main() {
+	debug.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 	debug.Print("Starting DoH poke...")
 	// Parse command line arguments
@@ -19,6 +19,8 @@
 // Flags:
 //   -host    The hosted address for this DNS server (required)
 //   -dns     DNS server address (default: 1.1.1.1:53)
+//   -debug-http  Serve pprof and runtime stats on this address (or DEBUG_HTTP env var)
+//   -suicide     Shut down gracefully after a minute and exit with code 124 (for testing)
 //
 // Examples:
 //   doh2dns -host example.com
@@ -35,16 +37,16 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"flag"
-	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Merith-TK/utils/pkg/debug"
+	"github.com/Merith-TK/utils/pkg/debug/debughttp"
 	"github.com/gorilla/mux"
 	"github.com/miekg/dns"
 )
@@ -79,14 +81,23 @@ var hostAddress string // Global variable to hold the host address
 func main() {
 	flag.StringVar(&hostAddress, "host", "", "The hosted address for this DNS server")
 	flag.StringVar(&dnsServer, "dns", "1.1.1.1", "DNS server address (e.g. 1.1.1.1:53). Leave empty to use the system resolver.")
+	debugAddr := flag.String("debug-http", os.Getenv("DEBUG_HTTP"), "Serve pprof, log level and runtime stats on this address (e.g. localhost:6060)")
+	debug.RegisterFlags(flag.CommandLine)
 	flag.Parse()
+	debug.Suicide(debug.DefaultSuicideTimeout)
+
+	if *debugAddr != "" {
+		if _, err := debughttp.Start(*debugAddr); err != nil {
+			fatal("Failed to start debug listener", "err", err)
+		}
+	}
 
 	if !strings.Contains(dnsServer, ":") {
 		dnsServer = strings.Join([]string{dnsServer, "53"}, ":")
 	}
 
 	if hostAddress == "" {
-		log.Fatal("Host address not provided")
+		fatal("Host address not provided")
 	}
 	if !debug.GetDebug() {
 		hostAddress = strings.Replace(hostAddress, "http://", "https://", 1)
@@ -95,10 +106,10 @@ func main() {
 		hostAddress = "https://" + hostAddress
 	}
 
-	log.Println("Host address:", hostAddress)
+	debug.Info("Host address", "host", hostAddress)
 	randStr, err := generateRandomString(16)
 	if err != nil {
-		log.Fatal("Failed to generate random string:", err)
+		fatal("Failed to generate random string", "err", err)
 	}
 
 	r := mux.NewRouter()
@@ -116,32 +127,30 @@ func main() {
 	defer cancel()
 
 	go func() {
-		log.Println("Starting Verification server on :8080")
-		log.Println(srv.ListenAndServe())
+		debug.Info("Starting verification server", "addr", srv.Addr)
+		debug.Info("Verification server stopped", "err", srv.ListenAndServe())
 	}()
 
 	resp, err := http.Get(hostAddress + "/dns-query")
 	if err != nil {
 		srv.Shutdown(ctx)
-		log.Fatal("Failed to get random string:", err)
+		fatal("Failed to get random string", "err", err)
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		srv.Shutdown(ctx)
-		log.Fatal("Failed to read response body:", err)
+		fatal("Failed to read response body", "err", err)
 	}
 	err = resp.Body.Close()
 	if err != nil {
 		srv.Shutdown(ctx)
-		log.Fatal("Failed to close response body:", err)
+		fatal("Failed to close response body", "err", err)
 	}
 
 	if string(body) != randStr {
-		log.Println("Random String Mismatch!")
-		log.Println("Expected:", randStr)
-		log.Println("Received:", string(body))
+		debug.Error("Random string mismatch", "expected", randStr, "received", string(body))
 	} else {
-		log.Println("Random String Match!")
+		debug.Info("Random string match")
 		srv.Shutdown(ctx)
 
 		startDoHServer()
@@ -149,12 +158,27 @@ func main() {
 }
 
 func startDoHServer() {
-	fmt.Println("Starting DoH to DNS server...")
+	debug.Info("Starting DoH to DNS server", "addr", ":8080")
 
 	r := mux.NewRouter()
 	r.HandleFunc("/dns-query", handleDNSRequest).Methods("GET")
 
-	log.Fatal(http.ListenAndServe(":8080", r))
+	srv := &http.Server{
+		Addr:    ":8080",
+		Handler: r,
+	}
+	debug.OnShutdown(srv.Shutdown)
+
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		fatal("DoH server stopped", "err", err)
+	}
+	os.Exit(debug.Shutdown(debug.ExitOK))
+}
+
+// fatal logs msg at error level and exits with status 1.
+func fatal(msg string, keyvals ...any) {
+	debug.Error(msg, keyvals...)
+	os.Exit(1)
 }
 
 func handleDNSRequest(w http.ResponseWriter, r *http.Request) {
@@ -201,7 +225,9 @@ func handleDNSRequest(w http.ResponseWriter, r *http.Request) {
 
 	in, _, err := dnsClient.Exchange(dnsQuery, "1.1.1.1:53")
 	if err != nil {
-		log.Fatalf("Failed to exchange: %v", err)
+		debug.Error("upstream exchange failed", "domain", domain, "type", qtype, "err", err)
+		http.Error(w, "Upstream DNS server failed", http.StatusBadGateway)
+		return
 	}
 
 	dohResp := DoHResponse{
@@ -1,139 +1,229 @@
-// Package main implements a WebSocket-based downtime monitoring client\r\n// that connects to monitoring servers and provides configurable alerting.\r\n//\r\n// The downtime-client connects to one or more monitoring servers via WebSocket\r\n// and maintains persistent connections with automatic reconnection. It provides\r\n// multiple notification methods when server connections are lost.\r\n//\r\n// Features:\r\n//   - Multi-server monitoring with concurrent connections\r\n//   - Dual WebSocket connections (main and heartbeat) per server\r\n//   - Automatic reconnection with configurable retry intervals\r\n//   - Multiple notification methods (beep, desktop notification, email, SMS)\r\n//   - JSON configuration file support\r\n//   - Connection health monitoring with ping/pong\r\n//   - Debug output integration\r\n//\r\n// Usage:\r\n//   downtime-client [-config config-file]\r\n//\r\n// Flags:\r\n//   -config    Path to JSON configuration file (default: servers.json)\r\n//\r\n// Configuration:\r\n//   The client uses a JSON configuration file specifying servers to monitor\r\n//   and notification preferences. Supports email, SMS, beep, and desktop\r\n//   notification methods with individual enable/disable controls.\r\n//\r\n// Connection Management:\r\n//   - Maintains two connections per server: main (/) and heartbeat (/heartbeat)\r\n//   - Automatic reconnection on connection loss\r\n//   - Health checks every 10 seconds\r\n//   - Graceful handling of network interruptions\r\npackage main
+// Package main implements a WebSocket-based downtime monitoring client
+// that connects to monitoring servers and provides configurable alerting.
+//
+// The downtime-client connects to one or more monitoring servers via WebSocket
+// and maintains persistent connections with automatic reconnection. It provides
+// multiple notification methods when server connections are lost.
+//
+// Features:
+//   - Multi-server monitoring with concurrent connections
+//   - Dual WebSocket connections (main and heartbeat) per server
+//   - Automatic reconnection with configurable retry intervals
+//   - Multiple notification methods (beep, desktop notification, email, SMS)
+//   - JSON configuration file support
+//   - Connection health monitoring with ping/pong
+//   - Debug output integration
+//
+// Usage:
+//   downtime-client [-config config-file]
+//
+// Flags:
+//   -config                Path to JSON configuration file (default: servers.json)
+//   -print-config-schema   Print the JSON Schema of the configuration file
+//   -init-config file      Write a documented example configuration file
+//
+// Configuration:
+//   The client uses a JSON configuration file specifying servers to monitor
+//   and notification preferences. Supports email, SMS, beep, and desktop
+//   notification methods with individual enable/disable controls.
+//   The email password may be a file:, env: or enc: secret reference
+//   (see config-secret) instead of cleartext.
+//
+// Connection Management:
+//   - Maintains two connections per server: main (/) and heartbeat (/heartbeat)
+//   - Automatic reconnection on connection loss
+//   - Health checks every 10 seconds
+//   - Graceful handling of network interruptions
+package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Merith-TK/utils/pkg/config"
 	"github.com/Merith-TK/utils/pkg/debug"
 	"github.com/gen2brain/beeep"
 	"github.com/gorilla/websocket"
 )
 
-var config Config
-
 type Config struct {
 	Notify struct {
 		Email struct {
-			Enabled bool   `json:"enabled,omitempty"`
-			Server  string `json:"server,omitempty"`
-			User    string `json:"user,omitempty"`
-			Pass    string `json:"pass,omitempty"`
-			From    string `json:"from,omitempty"`
-			To      string `json:"to,omitempty"`
-		} `json:"email,omitempty"`
+			Enabled bool   `json:"enabled,omitempty" doc:"Send an email when a server goes down"`
+			Server  string `json:"server,omitempty" validate:"hostport" doc:"SMTP server as host:port"`
+			User    string `json:"user,omitempty" doc:"SMTP user name"`
+			Pass    string `json:"pass,omitempty" secret:"true" doc:"SMTP password"`
+			From    string `json:"from,omitempty" doc:"Sender address, defaults to the user"`
+			To      string `json:"to,omitempty" doc:"Recipient address"`
+		} `json:"email,omitempty" doc:"Email alerts"`
 		Sms struct {
-			Enabled bool   `json:"enabled,omitempty"`
-			Phone   string `json:"phone,omitempty"`
-			Header  string `json:"header,omitempty"`
-		} `json:"sms,omitempty"`
+			Enabled bool   `json:"enabled,omitempty" doc:"Send a text message when a server goes down"`
+			Phone   string `json:"phone,omitempty" doc:"Phone number to send alerts to"`
+			Header  string `json:"header,omitempty" doc:"Text put before the alert message"`
+		} `json:"sms,omitempty" doc:"SMS alerts"`
 		Beep struct {
-			Enabled  bool    `json:"enabled,omitempty"`
-			Freq     float64 `json:"freq,omitempty"`
-			Duration int     `json:"duration,omitempty"`
-		} `json:"beep,omitempty"`
+			Enabled  bool    `json:"enabled,omitempty" doc:"Beep when a server goes down"`
+			Freq     float64 `json:"freq,omitempty" default:"440" validate:"min=20,max=20000" doc:"Beep frequency in Hz"`
+			Duration int     `json:"duration,omitempty" default:"200" validate:"min=1" doc:"Beep duration in milliseconds"`
+		} `json:"beep,omitempty" doc:"Beep alerts"`
 		Notification struct {
-			Enabled bool `json:"enabled,omitempty"`
-		} `json:"notification,omitempty"`
-	} `json:"notify,omitempty"`
-	Servers []string `json:"servers,omitempty"`
+			Enabled bool `json:"enabled,omitempty" doc:"Show a desktop notification when a server goes down"`
+		} `json:"notification,omitempty" doc:"Desktop notifications"`
+	} `json:"notify,omitempty" doc:"How to alert when a server goes down"`
+	Servers []string `json:"servers,omitempty" flag:"servers" doc:"Comma-separated servers to monitor, overrides the config file" validate:"min=1"`
 }
 
-var configFile string
-
-func init() {
-	flag.StringVar(&configFile, "config", "servers.json", "path to the config file")
+// Validate checks the notification settings that depend on each other.
+func (c *Config) Validate() error {
+	var errs []error
+	if email := c.Notify.Email; email.Enabled && (email.Server == "" || email.To == "") {
+		errs = append(errs, errors.New("notify.email: server and to are required when enabled"))
+	}
+	if sms := c.Notify.Sms; sms.Enabled && sms.Phone == "" {
+		errs = append(errs, errors.New("notify.sms: phone is required when enabled"))
+	}
+	return errors.Join(errs...)
 }
 
-func readConfigFromFile(filename string) error {
-	// Read the JSON file
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return err
+// emailFrom returns the sender address of email notifications, which defaults to the user.
+func (c *Config) emailFrom() string {
+	if c.Notify.Email.From == "" {
+		return c.Notify.Email.User
 	}
+	return c.Notify.Email.From
+}
 
-	// Unmarshal the JSON data into a Config struct
-	var servers Config
-	err = json.Unmarshal(data, &servers)
-	if err != nil {
-		return err
-	}
-	emailconfig := servers.Notify.Email
-	smsconfig := servers.Notify.Sms
-	beepconfig := servers.Notify.Beep
-	noticonfig := servers.Notify.Notification
-
-	// populate the default values for
-	if beepconfig.Freq == 0 {
-		beepconfig.Freq = beeep.DefaultFreq
-	}
-	if beepconfig.Duration == 0 {
-		beepconfig.Duration = beeep.DefaultDuration
-	}
+var configFile string
 
-	if emailconfig.From == "" {
-		emailconfig.From = emailconfig.User
-	}
+// schemaFlags are the -print-config-schema and -init-config flags.
+var schemaFlags *config.SchemaFlags
 
-	servers.Notify.Email = emailconfig
-	servers.Notify.Sms = smsconfig
-	servers.Notify.Beep = beepconfig
-	servers.Notify.Notification = noticonfig
-	config = servers
-	return nil
+// watcher holds the current config and reloads it when the file changes.
+var watcher *config.Watcher[Config]
+
+func init() {
+	flag.StringVar(&configFile, "config", "servers.json", "path to the config file")
+	debug.RegisterFlags(flag.CommandLine)
+	config.NewLoader(config.WithFlags(flag.CommandLine)).RegisterFlags(&Config{})
+	schemaFlags = config.RegisterSchemaFlags(flag.CommandLine)
+}
+
+// watchConfig loads the config from the defaults in Config, the file,
+// DOWNTIME_* environment variables (e.g. DOWNTIME_NOTIFY_EMAIL_PASS) and flags,
+// and sends every valid change of the file to reloaded. The email password
+// may be a file:, env: or enc: secret reference (see cmd/config-secret).
+func watchConfig(filename string, reloaded chan<- *Config) (*config.Watcher[Config], error) {
+	var initial Config
+	return config.Watch(filename, &initial, func(c *Config, err error) {
+		if err != nil {
+			debug.Warn("config reload failed, keeping previous config", "file", filename, "err", err)
+			return
+		}
+		debug.Info("config reloaded", "file", filename)
+		reloaded <- c
+	}, config.WithEnvPrefix("DOWNTIME"), config.WithFlags(flag.CommandLine))
 }
 
 func main() {
 	flag.Parse()
+	example := &Config{Servers: []string{"http://localhost:8080"}}
+	if done, err := schemaFlags.Handle(example); done {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	debug.Print("Downtime client started")
 
-	// Read the servers from the JSON file
-	err := readConfigFromFile(configFile)
+	// Read the servers from the config file and watch it for changes
+	reloaded := make(chan *Config)
+	var err error
+	watcher, err = watchConfig(configFile, reloaded)
 	if err != nil {
 		log.Fatal(err)
 	}
+	debug.OnShutdown(func(ctx context.Context) error {
+		return watcher.Close()
+	})
+
+	// Start connection attempts for each server, and follow config changes
+	monitored := make(map[string]context.CancelFunc)
+	cfg := watcher.Get()
+	for {
+		printConfig(cfg)
+		syncServers(monitored, cfg.Servers)
+		cfg = <-reloaded
+	}
+}
+
+// syncServers starts monitoring servers that are new in the config and
+// stops monitoring servers that were removed from it.
+func syncServers(monitored map[string]context.CancelFunc, servers []string) {
+	wanted := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		wanted[server] = true
+		if _, ok := monitored[server]; !ok {
+			ctx, cancel := context.WithCancel(debug.RootContext())
+			monitored[server] = cancel
+			go maintainConnection(ctx, server)
+		}
+	}
+	for server, cancel := range monitored {
+		if !wanted[server] {
+			debug.Info("server removed from config, disconnecting", "server", server)
+			cancel()
+			delete(monitored, server)
+		}
+	}
+}
+
+func printConfig(cfg *Config) {
 	fmt.Println("Config:")
 	fmt.Println("\tNotify:")
-	fmt.Println("\t\tEmail: ", config.Notify.Email.Enabled)
-	if config.Notify.Email.Enabled {
-		fmt.Println("\t\t\tServer:\t", config.Notify.Email.Server)
-		fmt.Println("\t\t\tUser:\t", config.Notify.Email.User)
-		fmt.Println("\t\t\tFrom:\t", config.Notify.Email.From)
-		fmt.Println("\t\t\tTo:\t", config.Notify.Email.To)
+	fmt.Println("\t\tEmail: ", cfg.Notify.Email.Enabled)
+	if cfg.Notify.Email.Enabled {
+		fmt.Println("\t\t\tServer:\t", cfg.Notify.Email.Server)
+		fmt.Println("\t\t\tUser:\t", cfg.Notify.Email.User)
+		fmt.Println("\t\t\tFrom:\t", cfg.emailFrom())
+		fmt.Println("\t\t\tTo:\t", cfg.Notify.Email.To)
 	}
-	fmt.Println("\t\tSms:   ", config.Notify.Sms.Enabled)
-	if config.Notify.Sms.Enabled {
-		fmt.Println("\t\t\tPhone:\t", config.Notify.Sms.Phone)
-		fmt.Println("\t\t\tHeader:\t", config.Notify.Sms.Header)
+	fmt.Println("\t\tSms:   ", cfg.Notify.Sms.Enabled)
+	if cfg.Notify.Sms.Enabled {
+		fmt.Println("\t\t\tPhone:\t", cfg.Notify.Sms.Phone)
+		fmt.Println("\t\t\tHeader:\t", cfg.Notify.Sms.Header)
 	}
-	fmt.Println("\t\tBeep:  ", config.Notify.Beep.Enabled)
-	if config.Notify.Beep.Enabled {
-		fmt.Println("\t\t\tFreq:\t ", strconv.FormatFloat(config.Notify.Beep.Freq, 'f', -1, 64)+"Hz")
-		fmt.Println("\t\t\tDuration:", strconv.FormatInt(int64(config.Notify.Beep.Duration), 10)+"ms")
+	fmt.Println("\t\tBeep:  ", cfg.Notify.Beep.Enabled)
+	if cfg.Notify.Beep.Enabled {
+		fmt.Println("\t\t\tFreq:\t ", strconv.FormatFloat(cfg.Notify.Beep.Freq, 'f', -1, 64)+"Hz")
+		fmt.Println("\t\t\tDuration:", strconv.FormatInt(int64(cfg.Notify.Beep.Duration), 10)+"ms")
 	}
-	fmt.Println("\t\tNotify:", config.Notify.Notification.Enabled)
+	fmt.Println("\t\tNotify:", cfg.Notify.Notification.Enabled)
 	fmt.Println("\tServers:")
-	for _, server := range config.Servers {
+	for _, server := range cfg.Servers {
 		fmt.Println("\t\t", server)
 	}
-
-	// Start connection attempts for each server
-	for _, server := range config.Servers {
-		go maintainConnection(server)
-	}
-
-	// Keep the main goroutine alive
-	select {}
 }
-func maintainConnection(server string) {
+
+// maintainConnection keeps both connections to server open until ctx is cancelled.
+func maintainConnection(ctx context.Context, server string) {
 	var mainConn, heartbeatConn *websocket.Conn
 	var err error
+	logger := debug.New("connection").With("server", server)
+	defer func() {
+		if mainConn != nil {
+			mainConn.Close()
+		}
+		if heartbeatConn != nil {
+			heartbeatConn.Close()
+		}
+	}()
 
 	for {
 		// Check the main connection
@@ -141,11 +231,13 @@ func maintainConnection(server string) {
 			// Attempt to connect to the main WebSocket server ("/")
 			mainConn, err = connectToServer(server)
 			if err != nil {
-				debug.Print(fmt.Sprintf("Failed to connect to main server %s: %v", server, err))
-				time.Sleep(10 * time.Second) // Retry after 10 seconds
+				logger.Warn("failed to connect to main server, retrying in 10s", "err", err)
+				if !sleep(ctx, 10*time.Second) { // Retry after 10 seconds
+					return
+				}
 				continue
 			}
-			debug.Print(fmt.Sprintf("Connected to main server %s", server))
+			logger.Print("Connected to main server")
 
 			// Start listening for messages from the main server
 			go handleServerMessages(server, mainConn)
@@ -154,21 +246,37 @@ func maintainConnection(server string) {
 		// Check the heartbeat connection
 		if heartbeatConn == nil || !isConnectionAlive(heartbeatConn) {
 			// Attempt to connect to the heartbeat WebSocket server ("/heartbeat")
-			debug.Print(fmt.Sprintf("Connecting to heartbeat server %s", server+"/heartbeat"))
+			logger.Print("Connecting to heartbeat server", server+"/heartbeat")
 			heartbeatConn, err = connectToServer(server + "/heartbeat")
 			if err != nil {
-				debug.Print(fmt.Sprintf("Failed to connect to heartbeat server %s: %v", server, err))
-				time.Sleep(10 * time.Second) // Retry after 10 seconds
+				logger.Warn("failed to connect to heartbeat server, retrying in 10s", "err", err)
+				if !sleep(ctx, 10*time.Second) { // Retry after 10 seconds
+					return
+				}
 				continue
 			}
-			debug.Print(fmt.Sprintf("Connected to heartbeat server %s", server+"/heartbeat"))
+			logger.Print("Connected to heartbeat server", server+"/heartbeat")
 
 			// Start listening for heartbeat messages
-			go handleHeartbeatMessages(server, heartbeatConn)
+			go handleHeartbeatMessages(ctx, server, heartbeatConn)
 		}
 
 		// Sleep for a while before checking the connection status again
-		time.Sleep(10 * time.Second)
+		if !sleep(ctx, 10*time.Second) {
+			return
+		}
+	}
+}
+
+// sleep waits for d and returns false if ctx is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
@@ -176,7 +284,7 @@ func handleServerMessages(url string, conn *websocket.Conn) {
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			debug.Print(fmt.Sprintf("Connection to server %s ended: %v", url, err))
+			debug.Warn("connection to server ended", "server", url, "err", err)
 			return
 		}
 
@@ -186,11 +294,15 @@ func handleServerMessages(url string, conn *websocket.Conn) {
 }
 
 // Handle messages from the heartbeat WebSocket server
-func handleHeartbeatMessages(url string, conn *websocket.Conn) {
+func handleHeartbeatMessages(ctx context.Context, url string, conn *websocket.Conn) {
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			debug.Print(fmt.Sprintf("Heartbeat connection to server %s ended: %v", url, err))
+			if ctx.Err() != nil {
+				// The server was removed from the config or the client is shutting down
+				return
+			}
+			debug.Warn("heartbeat connection to server ended", "server", url, "err", err)
 			handleDisconnection(url)
 			return
 		}
@@ -244,25 +356,24 @@ func connectToServer(urlString string) (*websocket.Conn, error) {
 }
 
 func handleDisconnection(url string) {
-	debug.SetTitle("Downtime Alert")
-	debug.Print(fmt.Sprintf("Connection to server %s ended", url))
-	if config.Notify.Beep.Enabled {
-		err := beeep.Beep(beeep.DefaultFreq, beeep.DefaultDuration)
+	alert := debug.New("Downtime Alert").With("server", url)
+	alert.Print("Connection to server ended")
+	cfg := watcher.Get()
+	if cfg.Notify.Beep.Enabled {
+		err := beeep.Beep(cfg.Notify.Beep.Freq, cfg.Notify.Beep.Duration)
 		if err != nil {
 			panic(err)
 		}
-		debug.Print("Beeped")
+		alert.Print("Beeped")
 	}
-	if config.Notify.Notification.Enabled {
+	if cfg.Notify.Notification.Enabled {
 		err := beeep.Notify("Downtime Alert", fmt.Sprintf("Connection to server %s ended", url), "")
 		if err != nil {
 			panic(err)
 		}
-		debug.Print("Notified")
+		alert.Print("Notified")
 	}
 
 	// TODO: Implement Email Notification
 	// TODO: Implement SMS Notification
-
-	debug.ResetTitle()
 }
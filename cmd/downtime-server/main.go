@@ -1,13 +1,47 @@
-// Package main implements a WebSocket-based downtime monitoring server\r\n// that tracks client connections and provides uptime information.\r\n//\r\n// The downtime-server provides a WebSocket-based monitoring system with\r\n// two distinct endpoints for different types of client communication.\r\n// It tracks active connections, handles ping/pong messaging, and provides\r\n// server uptime information to connected clients.\r\n//\r\n// Features:\r\n//   - Dual WebSocket endpoints (/ and /heartbeat)\r\n//   - Active connection tracking with automatic cleanup\r\n//   - Ping/pong messaging for connection health checks\r\n//   - Server uptime reporting\r\n//   - Automatic heartbeat transmission\r\n//   - Broken connection detection and removal\r\n//\r\n// Endpoints:\r\n//   /          - Main WebSocket endpoint for ping/pong and uptime requests\r\n//   /heartbeat - Dedicated heartbeat endpoint with automatic 5-second intervals\r\n//\r\n// Usage:\r\n//   downtime-server\r\n//\r\n// The server listens on port 8080 and accepts WebSocket connections.\r\n// Clients can send \"ping\" messages to receive \"pong\" responses,\r\n// or \"uptime\" messages to receive server uptime information.\r\n//\r\n// Connection Management:\r\n//   - Tracks all active connections with timestamps\r\n//   - Performs periodic health checks every 10 seconds\r\n//   - Automatically removes broken or inactive connections\r\n//   - Thread-safe connection management with mutex protection\r\npackage main
+// Package main implements a WebSocket-based downtime monitoring server
+// that tracks client connections and provides uptime information.
+//
+// The downtime-server provides a WebSocket-based monitoring system with
+// two distinct endpoints for different types of client communication.
+// It tracks active connections, handles ping/pong messaging, and provides
+// server uptime information to connected clients.
+//
+// Features:
+//   - Dual WebSocket endpoints (/ and /heartbeat)
+//   - Active connection tracking with automatic cleanup
+//   - Ping/pong messaging for connection health checks
+//   - Server uptime reporting
+//   - Automatic heartbeat transmission
+//   - Broken connection detection and removal
+//
+// Endpoints:
+//   /          - Main WebSocket endpoint for ping/pong and uptime requests
+//   /heartbeat - Dedicated heartbeat endpoint with automatic 5-second intervals
+//
+// Usage:
+//   downtime-server
+//
+// The server listens on port 8080 and accepts WebSocket connections.
+// Clients can send \"ping\" messages to receive \"pong\" responses,
+// or \"uptime\" messages to receive server uptime information.
+//
+// Connection Management:
+//   - Tracks all active connections with timestamps
+//   - Performs periodic health checks every 10 seconds
+//   - Automatically removes broken or inactive connections
+//   - Thread-safe connection management with mutex protection
+package main
 
 import (
+	"context"
 	"flag"
-	"log"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/Merith-TK/utils/pkg/debug"
+	"github.com/Merith-TK/utils/pkg/debug/debughttp"
 	"github.com/gorilla/websocket"
 )
 
@@ -22,23 +56,54 @@ var (
 )
 
 func main() {
+	debugAddr := flag.String("debug-http", os.Getenv("DEBUG_HTTP"), "Serve pprof, log level and runtime stats on this address (e.g. localhost:6060)")
+	debug.RegisterFlags(flag.CommandLine)
 	flag.Parse()
+	debug.Suicide(debug.DefaultSuicideTimeout)
 	startTime = time.Now()
-	log.Println("Downtime Server started")
+	debug.Info("Downtime Server started", "addr", ":8080")
 
-	// Define two different WebSocket endpoints
-	http.HandleFunc("/", handleWebSocket)          // For ping/pong and misc commands
-	http.HandleFunc("/heartbeat", handleHeartbeat) // For heartbeat
+	if *debugAddr != "" {
+		if _, err := debughttp.Start(*debugAddr); err != nil {
+			debug.Error("failed to start debug listener", "err", err)
+			os.Exit(1)
+		}
+	}
 
-	// Start the HTTP server
+	// Define two different WebSocket endpoints on a private mux, so debug
+	// handlers registered on http.DefaultServeMux are not exposed publicly
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleWebSocket)          // For ping/pong and misc commands
+	mux.HandleFunc("/heartbeat", handleHeartbeat) // For heartbeat
+
+	// Periodically remove broken connections until shutdown
 	go func() {
+		ticker := time.NewTicker(connCheckPeriod)
+		defer ticker.Stop()
 		for {
-			time.Sleep(connCheckPeriod)
-			checkConnections()
+			select {
+			case <-ticker.C:
+				checkConnections()
+			case <-debug.RootContext().Done():
+				return
+			}
 		}
 	}()
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	// Stop accepting connections first, then close the upgraded ones
+	srv := &http.Server{Addr: ":8080", Handler: mux}
+	debug.OnShutdown(func(ctx context.Context) error {
+		closeConnections()
+		return nil
+	})
+	debug.OnShutdown(srv.Shutdown)
+
+	// Start the HTTP server
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		debug.Error("server stopped", "err", err)
+		os.Exit(1)
+	}
+	os.Exit(debug.Shutdown(debug.ExitOK))
 }
 
 // Handle the main WebSocket connection at "/"
@@ -46,7 +111,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	debug.Print("New connection from:", r.RemoteAddr)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("Failed to upgrade connection:", err)
+		debug.Warn("failed to upgrade connection", "remote", r.RemoteAddr, "err", err)
 		return
 	}
 	debug.Print("Connection upgraded successfully at '/'")
@@ -65,7 +130,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("Failed to read message:", err)
+			debug.Warn("failed to read message", "remote", conn.RemoteAddr(), "err", err)
 			break
 		}
 
@@ -73,7 +138,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		if string(message) == "ping" {
 			err = conn.WriteMessage(websocket.TextMessage, []byte("pong"))
 			if err != nil {
-				log.Println("Failed to send pong:", err)
+				debug.Warn("failed to send pong", "remote", conn.RemoteAddr(), "err", err)
 				break
 			}
 		}
@@ -83,7 +148,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			uptime := time.Since(startTime).String()
 			err = conn.WriteMessage(websocket.TextMessage, []byte(uptime))
 			if err != nil {
-				log.Println("Failed to send uptime:", err)
+				debug.Warn("failed to send uptime", "remote", conn.RemoteAddr(), "err", err)
 				break
 			}
 		}
@@ -95,7 +160,7 @@ func handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 	debug.Print("New heartbeat connection from:", r.RemoteAddr)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("Failed to upgrade heartbeat connection:", err)
+		debug.Warn("failed to upgrade heartbeat connection", "remote", r.RemoteAddr, "err", err)
 		return
 	}
 	debug.Print("Connection upgraded successfully at '/heartbeat'")
@@ -120,13 +185,26 @@ func handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 		case <-ticker.C:
 			err := conn.WriteMessage(websocket.TextMessage, []byte("Heartbeat"))
 			if err != nil {
-				log.Println("Failed to send heartbeat:", err)
-				break
+				debug.Warn("failed to send heartbeat", "remote", conn.RemoteAddr(), "err", err)
+				return
 			}
+		case <-debug.RootContext().Done():
+			return
 		}
 	}
 }
 
+// closeConnections closes all tracked WebSocket connections
+func closeConnections() {
+	connsMutex.Lock()
+	defer connsMutex.Unlock()
+
+	for conn := range activeConns {
+		conn.Close()
+		delete(activeConns, conn)
+	}
+}
+
 // checkConnections periodically checks the status of connections
 func checkConnections() {
 	connsMutex.Lock()
@@ -137,7 +215,7 @@ func checkConnections() {
 		if time.Since(lastActivity) > connCheckPeriod {
 			err := conn.WriteMessage(websocket.PingMessage, nil)
 			if err != nil {
-				log.Printf("Connection to %s is broken, removing\n", conn.RemoteAddr())
+				debug.Warn("connection is broken, removing", "remote", conn.RemoteAddr())
 				conn.Close()
 				delete(activeConns, conn)
 			} else {
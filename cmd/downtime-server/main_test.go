@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+	"github.com/Merith-TK/utils/pkg/debug/debugtest"
+	"github.com/gorilla/websocket"
+)
+
+func TestHandleWebSocket(t *testing.T) {
+	startTime = time.Now()
+	srv := httptest.NewServer(http.HandlerFunc(handleWebSocket))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	t.Run("plain request", func(t *testing.T) {
+		rec := debugtest.CaptureAt(t, debug.LevelDebug)
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+		}
+		rec.Expect(debugtest.Msg("New connection from:"))
+		rec.Expect(debugtest.Level(debug.LevelWarn), debugtest.Msg("failed to upgrade connection"), debugtest.HasField("remote"))
+	})
+
+	t.Run("ping and uptime", func(t *testing.T) {
+		rec := debugtest.CaptureAt(t, debug.LevelDebug)
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range []string{"ping", "uptime"} {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				t.Fatal(err)
+			}
+			_, reply, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg == "ping" && string(reply) != "pong" {
+				t.Errorf("reply to ping %q, want pong", reply)
+			}
+			if _, err := time.ParseDuration(string(reply)); msg == "uptime" && err != nil {
+				t.Errorf("reply to uptime %q is not a duration", reply)
+			}
+		}
+		conn.Close()
+
+		// The handler logs the closed connection and stops tracking it.
+		tracked := func() int {
+			connsMutex.Lock()
+			defer connsMutex.Unlock()
+			return len(activeConns)
+		}
+		for deadline := time.Now().Add(5 * time.Second); tracked() > 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := tracked(); n != 0 {
+			t.Errorf("%d connections still tracked", n)
+		}
+		rec.Expect(debugtest.Msg("Connection upgraded successfully"))
+		rec.Expect(debugtest.Level(debug.LevelWarn), debugtest.Msg("failed to read message"), debugtest.HasField("err"))
+		rec.ExpectNone(debugtest.Level(debug.LevelError))
+	})
+}
@@ -1,4 +1,35 @@
-// Package main implements a Git repository organization utility that automatically\r\n// sorts repositories into directory structures based on their remote origin URLs.\r\n//\r\n// The git-sort-repo utility scans directories for Git repositories and reorganizes\r\n// them into a hierarchical structure that mirrors their remote origin URLs.\r\n// This helps maintain organized project structures that reflect repository sources.\r\n//\r\n// Features:\r\n//   - Automatic Git repository detection via .git directory presence\r\n//   - Remote origin URL extraction and parsing\r\n//   - Directory structure creation based on URL hierarchy\r\n//   - Dry-run mode for safe preview of operations\r\n//   - Support for HTTPS, HTTP, and SSH Git URLs\r\n//   - Batch processing of multiple directories\r\n//   - Collision detection for existing destinations\r\n//\r\n// Usage:\r\n//   git-sort-repo [-d] [directories...]\r\n//\r\n// Flags:\r\n//   -d    Dry run mode - show what would be moved without making changes\r\n//\r\n// Examples:\r\n//   git-sort-repo                    # Sort all repos in current directory\r\n//   git-sort-repo -d                 # Preview sorting without changes\r\n//   git-sort-repo ~/projects ~/work  # Sort repos in specific directories\r\n//\r\n// The tool creates directory structures like:\r\n//   github.com/user/repo-name/\r\n//   gitlab.com/group/project-name/\r\n//   bitbucket.org/team/repository/\r\npackage main
+// Package main implements a Git repository organization utility that automatically
+// sorts repositories into directory structures based on their remote origin URLs.
+//
+// The git-sort-repo utility scans directories for Git repositories and reorganizes
+// them into a hierarchical structure that mirrors their remote origin URLs.
+// This helps maintain organized project structures that reflect repository sources.
+//
+// Features:
+//   - Automatic Git repository detection via .git directory presence
+//   - Remote origin URL extraction and parsing
+//   - Directory structure creation based on URL hierarchy
+//   - Dry-run mode for safe preview of operations
+//   - Support for HTTPS, HTTP, and SSH Git URLs
+//   - Batch processing of multiple directories
+//   - Collision detection for existing destinations
+//
+// Usage:
+//   git-sort-repo [-d] [directories...]
+//
+// Flags:
+//   -d    Dry run mode - show what would be moved without making changes
+//
+// Examples:
+//   git-sort-repo                    # Sort all repos in current directory
+//   git-sort-repo -d                 # Preview sorting without changes
+//   git-sort-repo ~/projects ~/work  # Sort repos in specific directories
+//
+// The tool creates directory structures like:
+//   github.com/user/repo-name/
+//   gitlab.com/group/project-name/
+//   bitbucket.org/team/repository/
+package main
 
 import (
 	"flag"
@@ -17,6 +48,7 @@ var dryRun bool
 
 func init() {
 	flag.BoolVar(&dryRun, "d", false, "Dry run")
+	debug.RegisterFlags(flag.CommandLine)
 
 	flag.Parse()
 }
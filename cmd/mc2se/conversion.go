@@ -1,9 +1,9 @@
 package main
 
 import (
-	"encoding/json"
 	"os"
 
+	"github.com/Merith-TK/utils/pkg/config"
 	"github.com/Merith-TK/utils/pkg/debug"
 	"github.com/elvis972602/go-litematica-tools/schematic"
 	"github.com/lucasb-eyer/go-colorful"
@@ -71,11 +71,17 @@ func convertBlock(block schematic.BlockState) ([]float64, string, string) {
 
 var blockDefinitions []blockDefinition
 
+// exampleDefinitions are written to a new definitions file.
+var exampleDefinitions = []blockDefinition{
+	{Block: "minecraft:stone", Color: "#bfbfbf", BlockType: "LargeHeavyBlockArmorBlock"},
+	{Block: "minecraft:oak_planks", Color: "#835432", BlockType: "LargeHeavyBlockArmorBlock"},
+}
+
 type blockDefinition struct {
-	Block     string `json:"block"`
-	Color     string `json:"color"`
-	BlockType string `json:"blockType,omitempty"`
-	BlockSkin string `json:"blockSkin,omitempty"`
+	Block     string `json:"block" yaml:"block" doc:"Minecraft block ID, e.g. minecraft:stone"`
+	Color     string `json:"color" yaml:"color" doc:"Hex color of the Space Engineers block, e.g. #bfbfbf"`
+	BlockType string `json:"blockType,omitempty" yaml:"blockType,omitempty" doc:"Space Engineers block subtype, e.g. LargeHeavyBlockArmorBlock"`
+	BlockSkin string `json:"blockSkin,omitempty" yaml:"blockSkin,omitempty" doc:"Space Engineers armor skin, e.g. Concrete_Armor"`
 }
 
 func loadDefaultDefinitions() {
@@ -98,25 +104,14 @@ func loadDefaultDefinitions() {
 func loadDefinitions() {
 	debug.Print("Loading definitions")
 	if _, err := os.Stat(definitionsFile); os.IsNotExist(err) {
-		// generate default definitions for stone and oak_planks
-		exampleDefinitions := []blockDefinition{
-			{Block: "minecraft:stone", Color: "#bfbfbf", BlockType: "LargeHeavyBlockArmorBlock"},
-			{Block: "minecraft:oak_planks", Color: "#835432", BlockType: "LargeHeavyBlockArmorBlock"},
-		}
-		jsonData, err := json.MarshalIndent(exampleDefinitions, "", "    ")
+		// Start a new file from the example definitions, in the format
+		// of its extension, e.g. definitions.yaml.
+		err = config.Save(definitionsFile, exampleDefinitions)
 		if err != nil {
 			panic(err)
 		}
-		err = os.WriteFile(definitionsFile, jsonData, 0644)
-		if err != nil {
-			panic(err)
-		}
-	}
-	definitionsFile, err := os.ReadFile(definitionsFile)
-	if err != nil {
-		panic(err)
 	}
-	err = json.Unmarshal(definitionsFile, &blockDefinitions)
+	err := config.Load(definitionsFile, &blockDefinitions)
 	if err != nil {
 		panic(err)
 	}
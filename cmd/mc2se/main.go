@@ -21,11 +21,14 @@
 //   -o    Output Space Engineers blueprint file (default: output.sbc)
 //   -d    Custom block definitions JSON file (default: definitions.json)
 //   -s    Use small grid blocks instead of large grid
+//   -print-config-schema   Print the JSON Schema of the definitions file
+//   -init-config file      Write a documented example definitions file
 //
 // Examples:
 //   mc2se -i castle.litematic -o castle.sbc
 //   mc2se -i house.litematic -o house.sbc -s
 //   mc2se -i structure.litematic -d custom_blocks.json
+//   mc2se -init-config custom_blocks.yaml
 //
 // The converter processes each block in the schematic, maps it to appropriate
 // Space Engineers blocks, and generates a complete .sbc blueprint file.
@@ -37,6 +40,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/Merith-TK/utils/pkg/config"
 	"github.com/Merith-TK/utils/pkg/debug"
 	"github.com/elvis972602/go-litematica-tools/schematic"
 )
@@ -45,16 +49,25 @@ var inputFile string
 var outputFile string
 var definitionsFile string
 var smallGrid bool
+var schemaFlags *config.SchemaFlags
 
 func init() {
 	flag.StringVar(&inputFile, "i", "", "input file name")
 	flag.StringVar(&outputFile, "o", "output.sbc", "output file name")
-	flag.StringVar(&definitionsFile, "d", "definitions.json", "custom definitions file")
+	flag.StringVar(&definitionsFile, "d", "definitions.json", "custom definitions file (.json or .yaml)")
 	flag.BoolVar(&smallGrid, "s", false, "use small grid blocks")
+	debug.RegisterFlags(flag.CommandLine)
+	schemaFlags = config.RegisterSchemaFlags(flag.CommandLine)
 	flag.Parse()
 }
 
 func main() {
+	if done, err := schemaFlags.Handle(exampleDefinitions); done {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	if inputFile == "" {
 		log.Fatal("Input file not specified")
@@ -89,6 +102,10 @@ func main() {
 	loadDefinitions()
 	convertColors()
 
+	// Log the first few blocks, then only every 1000th, so large
+	// schematics do not flood the terminal
+	blockSampler := debug.NewSampler(5, 1000, 0)
+
 	var blocklist string
 	for x := 0; x < int(xSize); x++ {
 		for y := 0; y < int(ySize); y++ {
@@ -97,19 +114,19 @@ func main() {
 				if blockState.Name == "minecraft:air" {
 					continue
 				}
-				log.Println("Converting block:", blockState.Name)
+				blockLog := debug.New(blockState.Name).WithSampler(blockSampler)
+				blockLog.Info("Converting block", "x", x, "y", y, "z", z)
 				color, blockType, blockSkin := convertBlock(blockState)
-				debug.SetTitle(blockState.Name)
-				debug.Print("> ", blockType, color)
-				debug.Print("> ", blockSkin)
-				debug.Print("> ", x, y, z)
-				debug.ResetTitle()
+				blockLog.Print("> ", blockType, color)
+				blockLog.Print("> ", blockSkin)
+				blockLog.Print("> ", x, y, z)
 
 				// todo: handle custom skins in conversion.go
 				blocklist += writeBlock(blockType, color, []int{x, y, z}, project.RegionName, blockSkin)
 			}
 		}
 	}
+	blockSampler.Flush()
 
 	// write the blueprint
 	xmlOutput := xmlHeader + blocklist + xmlFooter
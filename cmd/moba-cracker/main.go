@@ -20,6 +20,7 @@ func init() {
 	fmt.Println("Author: Merith-TK")
 	fmt.Println("Version: 1.0")
 
+	debug.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 }
 
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"archive/zip"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,6 +15,7 @@ import (
 )
 
 func main() {
+	debug.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	// Always enable debug for testing
@@ -146,12 +145,13 @@ func testArchivePackage() {
 	}
 
 	// Create zip file manually for testing
-	if err := createTestZip(testDir, zipFile); err != nil {
+	if err := archive.Zip(testDir, zipFile); err != nil {
 		fmt.Printf("Failed to create test zip: %v\n", err)
 		// Cleanup and return early
 		os.RemoveAll(testDir)
 		return
 	}
+	fmt.Printf("Created test zip: %s\n", zipFile)
 
 	// Test Unzip
 	if err := archive.Unzip(zipFile, extractDir); err != nil {
@@ -181,66 +181,6 @@ func testArchivePackage() {
 	os.Remove(zipFile)
 }
 
-func createTestZip(sourceDir, zipFile string) error {
-	// Create the zip file
-	zipFileHandle, err := os.Create(zipFile)
-	if err != nil {
-		return fmt.Errorf("failed to create zip file: %w", err)
-	}
-	defer zipFileHandle.Close()
-
-	// Create zip writer
-	zipWriter := zip.NewWriter(zipFileHandle)
-	defer zipWriter.Close()
-
-	// Walk through source directory and add files to zip
-	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Get relative path for zip entry
-		relPath, err := filepath.Rel(sourceDir, path)
-		if err != nil {
-			return err
-		}
-
-		// Skip the root directory itself
-		if relPath == "." {
-			return nil
-		}
-
-		// For directories, add trailing slash
-		if info.IsDir() {
-			relPath = relPath + "/"
-		}
-
-		// Create zip entry
-		zipEntry, err := zipWriter.Create(relPath)
-		if err != nil {
-			return err
-		}
-
-		// If it's a directory, we're done (just created the entry)
-		if info.IsDir() {
-			return nil
-		}
-
-		// If it's a file, copy its contents
-		file, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		_, err = io.Copy(zipEntry, file)
-		return err
-	})
-
-	fmt.Printf("Created test zip: %s\n", zipFile)
-	return err
-}
-
 func testDriveutilPackage() {
 	fmt.Println("\n--- Testing driveutil package ---")
 
@@ -0,0 +1,200 @@
+package archive
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrUnsafePath is returned for an entry whose path or link target would
+	// end up outside the destination directory.
+	ErrUnsafePath = errors.New("path escapes the destination")
+	// ErrTooLarge is returned when the extracted data exceeds WithMaxSize.
+	ErrTooLarge = errors.New("uncompressed size exceeds the limit")
+	// ErrTooManyFiles is returned when an archive has more entries than WithMaxFiles allows.
+	ErrTooManyFiles = errors.New("too many entries")
+	// ErrRatio is returned when an entry expands more than WithMaxRatio allows.
+	ErrRatio = errors.New("compression ratio exceeds the limit")
+	// ErrFormat is returned for files that are not an archive of a known Format.
+	ErrFormat = errors.New("unknown archive format")
+	// ErrExists is returned for an entry whose file exists, with ConflictError.
+	ErrExists = errors.New("file already exists")
+)
+
+// EntryError records the archive entry at which extraction failed. Use
+// errors.Is with ErrUnsafePath, ErrTooLarge, ErrTooManyFiles or ErrRatio to
+// find the reason.
+type EntryError struct {
+	Name string
+	Err  error
+}
+
+// Error implements error.
+func (e *EntryError) Error() string {
+	return "archive: " + e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// Option configures extraction or archive creation.
+type Option func(*options)
+
+// options holds the settings of an extraction or archive creation. Zero
+// limits are unlimited.
+type options struct {
+	maxSize  int64
+	maxFiles int
+	maxRatio float64
+	format   Format
+
+	include []string
+	exclude []string
+	modTime time.Time
+	store   []string
+
+	strip    int
+	rename   func(name string) string
+	conflict Conflict
+	progress func(Progress)
+}
+
+// newOptions returns the options configured by opts.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMaxSize limits the total uncompressed size of the extracted files to n bytes.
+func WithMaxSize(n int64) Option {
+	return func(o *options) {
+		o.maxSize = n
+	}
+}
+
+// WithMaxFiles limits the number of entries, including directories and links.
+func WithMaxFiles(n int) Option {
+	return func(o *options) {
+		o.maxFiles = n
+	}
+}
+
+// WithMaxRatio limits how many times larger than its compressed size an
+// entry may become, e.g. 100. For compressed tar archives, which have no
+// per-entry sizes, it limits the ratio of the whole stream. Small files of
+// repeated bytes compress well, so very low limits reject legitimate
+// archives.
+func WithMaxRatio(r float64) Option {
+	return func(o *options) {
+		o.maxRatio = r
+	}
+}
+
+// WithInclude only adds or extracts files that match one of the patterns.
+// Patterns use path.Match syntax; one without a '/' matches any path
+// element, such as "*.go", and one with a '/' matches the path from the
+// root, such as "docs/*". A pattern that matches a directory selects
+// everything in it. Extraction matches the path in the archive, before
+// WithStripComponents and WithRename. A tar hard link is only extracted
+// with the file it links to, as the archive stores the content once, with
+// that file; a link whose target is left out is skipped even if its own
+// path matches.
+func WithInclude(patterns ...string) Option {
+	return func(o *options) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithExclude leaves out files and directories that match one of the
+// patterns, written as for WithInclude, e.g. ".git" or "*.tmp". Exclusion
+// wins over inclusion.
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithModTime sets the modification time of every created entry, so that
+// archives of the same files are byte-for-byte identical. Tar entries also
+// lose their owner, which differs between machines.
+func WithModTime(t time.Time) Option {
+	return func(o *options) {
+		o.modTime = t
+	}
+}
+
+// WithStore stores files that match one of the patterns, written as for
+// WithInclude, without compression; others are deflated. Use it for data
+// that is already compressed, such as "*.png", or "*" to store everything.
+func WithStore(patterns ...string) Option {
+	return func(o *options) {
+		o.store = append(o.store, patterns...)
+	}
+}
+
+// WithStripComponents removes the first n elements from the path of every
+// extracted entry, like tar --strip-components. Entries with no more than
+// n elements, such as the top directory, are skipped. Absolute names and
+// names that leave the destination fail before any elements are removed.
+func WithStripComponents(n int) Option {
+	return func(o *options) {
+		o.strip = n
+	}
+}
+
+// WithRename calls fn with the '/'-separated path of every extracted entry,
+// after WithStripComponents, and extracts the entry to the path it returns
+// instead, or skips it if that is "". The new path is subject to the same
+// safety checks. Hard links follow their renamed target.
+func WithRename(fn func(name string) string) Option {
+	return func(o *options) {
+		o.rename = fn
+	}
+}
+
+// Conflict says what extraction does with an entry whose file exists.
+// Directories are always merged.
+type Conflict int
+
+const (
+	ConflictOverwrite Conflict = iota // replace the file; the default
+	ConflictSkip                      // keep the file
+	ConflictNewer                     // replace the file if the entry is newer
+	ConflictError                     // fail with ErrExists
+)
+
+// WithConflict sets what extraction does with existing files.
+func WithConflict(c Conflict) Option {
+	return func(o *options) {
+		o.conflict = c
+	}
+}
+
+// Progress reports how far an extraction is.
+type Progress struct {
+	Name      string // entry path in the archive
+	Entry     int    // position of the entry in the archive, from 1
+	Entries   int    // number of entries in the archive, or 0 if unknown, as for tar
+	Written   int64  // bytes of the entry written so far
+	Size      int64  // size of the entry
+	Total     int64  // bytes written by the extraction so far
+	TotalSize int64  // uncompressed size of the archive, or 0 if unknown
+	Done      bool   // whether the entry is finished
+	Skipped   bool   // whether the entry was skipped because its file exists
+}
+
+// WithProgress calls fn when an extracted entry starts, as its data is
+// written, and when it is done. Entries left out by WithInclude, WithExclude,
+// WithStripComponents or WithRename are not reported. fn is called on the
+// extracting goroutine, so UIs should hand the update to their own thread
+// if needed.
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
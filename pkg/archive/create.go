@@ -0,0 +1,136 @@
+package archive
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// create writes the contents of srcDir to a new archive at dst, removing
+// dst on failure.
+func create(srcDir, dst string, format Format, opts *options) (err error) {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(dst)
+		}
+	}()
+	sources, err := collect(srcDir, dst, opts)
+	if err != nil {
+		return err
+	}
+
+	if format == FormatZip {
+		err = writeZip(f, sources, opts)
+	} else {
+		err = writeCompressedTar(f, format, sources, opts)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// source is a file or directory to add to an archive.
+type source struct {
+	name string // path in the archive, separated by '/'; directories end in '/'
+	path string // path on disk
+	info fs.FileInfo
+}
+
+// collect walks srcDir in lexical order and returns the entries to archive,
+// filtered by the include and exclude patterns. skip is a file that is
+// never added, such as the archive being written.
+func collect(srcDir, skip string, opts *options) ([]source, error) {
+	skipInfo, _ := os.Stat(skip)
+	var sources []source
+	err := filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		if matchAny(opts.exclude, name) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if skipInfo != nil && os.SameFile(info, skipInfo) {
+			return nil
+		}
+		if d.IsDir() {
+			sources = append(sources, source{name: name + "/", path: p, info: info})
+			return nil
+		}
+		if len(opts.include) > 0 && !matchAny(opts.include, name) {
+			return nil
+		}
+		sources = append(sources, source{name: name, path: p, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.include) > 0 {
+		sources = dropUnusedDirs(sources)
+	}
+	return sources, nil
+}
+
+// dropUnusedDirs removes the directories that hold no file from sources,
+// so that include patterns do not produce empty directory trees.
+func dropUnusedDirs(sources []source) []source {
+	used := make(map[string]bool)
+	for _, s := range sources {
+		if !s.info.IsDir() {
+			for dir := path.Dir(s.name); dir != "."; dir = path.Dir(dir) {
+				used[dir+"/"] = true
+			}
+		}
+	}
+	out := sources[:0]
+	for _, s := range sources {
+		if !s.info.IsDir() || used[s.name] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// matchAny reports whether name, or one of its parent directories, matches
+// one of the patterns. Patterns use path.Match syntax; a pattern without a
+// '/' is matched against each path element, so "*.log" and ".git" apply at
+// any depth, while "docs/*.md" is matched against the whole path.
+func matchAny(patterns []string, name string) bool {
+	name = strings.TrimSuffix(name, "/")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		for p := name; p != "." && p != "/"; p = path.Dir(p) {
+			target := p
+			if !strings.Contains(pattern, "/") {
+				target = path.Base(p)
+			}
+			if ok, _ := path.Match(pattern, target); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// writeTree creates the files in root with the given contents. Names ending
+// in '/' are created as directories.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// archiveNames returns the entry names of the ZIP or tar archive at path.
+func archiveNames(t *testing.T, path string) []string {
+	t.Helper()
+	fsys, err := OpenFS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	var names []string
+	switch fsys.Format() {
+	case FormatZip:
+		for _, f := range fsys.fsys.(*zip.Reader).File {
+			names = append(names, f.Name)
+		}
+	default:
+		for name, e := range fsys.fsys.(*tarFS).entries {
+			if e.index >= 0 {
+				names = append(names, e.hdr.Name)
+			} else if name != "." {
+				names = append(names, "implied "+name)
+			}
+		}
+		sort.Strings(names)
+	}
+	return names
+}
+
+// sampleTree is a source directory for the creation tests.
+var sampleTree = map[string]string{
+	"a.go":              "package a",
+	"a.tmp":             "scratch",
+	"docs/readme.md":    "# docs",
+	"docs/img/logo.png": "\x89PNG",
+	".git/config":       "[core]",
+	"empty/":            "",
+	"sub/b.go":          "package b",
+}
+
+func TestCreateFilters(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, sampleTree)
+	all := []string{".git/", ".git/config", "a.go", "a.tmp", "docs/", "docs/img/", "docs/img/logo.png", "docs/readme.md", "empty/", "sub/", "sub/b.go"}
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"everything", nil, all},
+		{"exclude", []Option{WithExclude(".git", "*.tmp")},
+			[]string{"a.go", "docs/", "docs/img/", "docs/img/logo.png", "docs/readme.md", "empty/", "sub/", "sub/b.go"}},
+		{"include by extension drops unused directories", []Option{WithInclude("*.go")},
+			[]string{"a.go", "sub/", "sub/b.go"}},
+		{"include a directory", []Option{WithInclude("docs/*")},
+			[]string{"docs/", "docs/img/", "docs/img/logo.png", "docs/readme.md"}},
+		{"include a nested directory", []Option{WithInclude("img")},
+			[]string{"docs/", "docs/img/", "docs/img/logo.png"}},
+		{"exclude wins", []Option{WithInclude("*.go"), WithExclude("sub")},
+			[]string{"a.go"}},
+		{"trailing slash", []Option{WithExclude("docs/", ".git/", "empty/")},
+			[]string{"a.go", "a.tmp", "sub/", "sub/b.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, ext := range []string{".zip", ".tar"} {
+				dst := filepath.Join(t.TempDir(), "out"+ext)
+				if err := Create(src, dst, tt.opts...); err != nil {
+					t.Fatal(err)
+				}
+				if got := archiveNames(t, dst); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s: got %q, want %q", ext, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestZipSkipsItself(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"a": "1"})
+	dst := filepath.Join(src, "out.zip")
+	if err := Zip(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := archiveNames(t, dst); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("got %q, want only a", got)
+	}
+}
+
+func TestCreateReproducible(t *testing.T) {
+	epoch := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tar.zst", ".tar.xz"} {
+		t.Run(ext, func(t *testing.T) {
+			var archives [][]byte
+			for i, mtime := range []time.Time{time.Now(), time.Now().Add(-time.Hour)} {
+				src := t.TempDir()
+				writeTree(t, src, sampleTree)
+				filepath.Walk(src, func(p string, _ os.FileInfo, _ error) error {
+					return os.Chtimes(p, mtime, mtime)
+				})
+				dst := filepath.Join(t.TempDir(), "out"+ext)
+				if err := Create(src, dst, WithModTime(epoch)); err != nil {
+					t.Fatal(err)
+				}
+				data, err := os.ReadFile(dst)
+				if err != nil {
+					t.Fatal(err)
+				}
+				archives = append(archives, data)
+				if i > 0 && !bytes.Equal(archives[0], data) {
+					t.Errorf("archives of the same files differ")
+				}
+			}
+		})
+	}
+}
+
+func TestCreateModTime(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"a": "1"})
+	epoch := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	dst := filepath.Join(t.TempDir(), "out.tar")
+	if err := Create(src, dst, WithModTime(epoch)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	hdr, err := tar.NewReader(f).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hdr.ModTime.Equal(epoch) || hdr.Uid != 0 || hdr.Gid != 0 || hdr.Uname != "" || hdr.Gname != "" {
+		t.Errorf("header %+v, want the fixed time and no owner", hdr)
+	}
+
+	dst = filepath.Join(t.TempDir(), "out.zip")
+	if err := Zip(src, dst, WithModTime(epoch)); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if got := zr.File[0].Modified; !got.Equal(epoch) {
+		t.Errorf("modified %v, want %v", got, epoch)
+	}
+}
+
+func TestZipStore(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, sampleTree)
+	tests := []struct {
+		name   string
+		opts   []Option
+		stored []string
+	}{
+		{"default", nil, []string{".git/", "docs/", "docs/img/", "empty/", "sub/"}},
+		{"pattern", []Option{WithStore("*.png", "docs/*.md")},
+			[]string{".git/", "docs/", "docs/img/", "docs/img/logo.png", "docs/readme.md", "empty/", "sub/"}},
+		{"everything", []Option{WithStore("*")}, []string{".git/", ".git/config", "a.go", "a.tmp", "docs/", "docs/img/",
+			"docs/img/logo.png", "docs/readme.md", "empty/", "sub/", "sub/b.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "out.zip")
+			if err := Zip(src, dst, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			zr, err := zip.OpenReader(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			var stored []string
+			for _, f := range zr.File {
+				if f.Method == zip.Store {
+					stored = append(stored, f.Name)
+				} else if f.Method != zip.Deflate {
+					t.Errorf("%s has method %d", f.Name, f.Method)
+				}
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil || string(data) != sampleTree[f.Name] {
+					t.Errorf("%s holds %q, %v", f.Name, data, err)
+				}
+			}
+			if !reflect.DeepEqual(stored, tt.stored) {
+				t.Errorf("stored %q, want %q", stored, tt.stored)
+			}
+		})
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"a": "1"})
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		src  string
+		dst  string
+		opts []Option
+		want error
+	}{
+		{"unknown extension", src, "out.rar", nil, ErrFormat},
+		{"bzip2", src, "out.tar.bz2", nil, nil},
+		{"missing source", filepath.Join(dir, "missing"), "out.zip", nil, os.ErrNotExist},
+		{"missing source tar", filepath.Join(dir, "missing"), "out.tar.gz", nil, os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(dir, tt.dst)
+			err := Create(tt.src, dst, tt.opts...)
+			if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if _, err := os.Stat(dst); err == nil {
+				t.Errorf("%s was left behind", tt.dst)
+			}
+		})
+	}
+	if err := Create(src, filepath.Join(dir, "out.rar"), WithFormat(FormatTarGz)); err != nil {
+		t.Errorf("WithFormat: %v", err)
+	}
+	if f, _ := Detect(filepath.Join(dir, "out.rar")); f != FormatTarGz {
+		t.Errorf("WithFormat(FormatTarGz) wrote %v", f)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateKeepsModes(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"run.sh": "#!/bin/sh", "secret": "x", "dir/plain": "y"})
+	modes := map[string]os.FileMode{"run.sh": 0755, "secret": 0600, "dir/plain": 0644}
+	for name, mode := range modes {
+		if err := os.Chmod(filepath.Join(src, name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("dir/plain", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ext := range []string{".zip", ".tar", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "out"+ext)
+			if err := Create(src, dst); err != nil {
+				t.Fatal(err)
+			}
+			dest := t.TempDir()
+			if err := Extract(dst, dest); err != nil {
+				t.Fatal(err)
+			}
+			for name, mode := range modes {
+				info, err := os.Lstat(filepath.Join(dest, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode() != mode {
+					t.Errorf("%s has mode %v, want %v", name, info.Mode(), mode)
+				}
+			}
+			if target, err := os.Readlink(filepath.Join(dest, "link")); err != nil || target != "dir/plain" {
+				t.Errorf("link points to %q, %v; want dir/plain", target, err)
+			}
+		})
+	}
+}
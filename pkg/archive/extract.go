@@ -0,0 +1,390 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// entry is an archive member, independent of the archive format.
+type entry struct {
+	name       string      // path in the archive, separated by '/'
+	mode       fs.FileMode // type and permission bits
+	linkname   string      // target of a symlink
+	hardlink   string      // for a hard link, the path of the linked entry
+	size       int64       // uncompressed size
+	compressed int64       // compressed size, or 0 if unknown
+	modTime    time.Time
+	open       func() (io.ReadCloser, error)
+}
+
+// extractor writes entries below dest while enforcing the options.
+type extractor struct {
+	dest   string // absolute destination, with symlinks resolved
+	opts   *options
+	stream *countReader // compressed input of a tar archive, or nil
+	files  int
+	total  int64
+
+	// For progress reports; the declared sizes are 0 if unknown.
+	index     int
+	entries   int
+	totalSize int64
+}
+
+// newExtractor creates dest if needed and returns an extractor for it.
+func newExtractor(dest string, opts *options) (*extractor, error) {
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	return &extractor{dest: resolved, opts: opts}, nil
+}
+
+// extract writes one entry, unless the options leave it out. Errors are
+// wrapped in an *EntryError.
+func (x *extractor) extract(e entry) error {
+	x.index++
+	// Unsafe names are rejected before the options apply, so that stripping
+	// cannot turn them into safe ones.
+	if unsafeName(e.name) || e.hardlink != "" && unsafeName(e.hardlink) {
+		return &EntryError{Name: e.name, Err: ErrUnsafePath}
+	}
+	name, ok := x.rename(e.name)
+	if !ok {
+		return nil
+	}
+	if e.hardlink != "" {
+		// The content of a hard link is stored with its target, earlier in
+		// the stream, so a link whose target was left out is left out too.
+		if e.hardlink, ok = x.rename(e.hardlink); !ok {
+			return nil
+		}
+	}
+	p := &Progress{Name: e.name, Entry: x.index, Entries: x.entries, Size: e.size, TotalSize: x.totalSize}
+	if err := x.extractEntry(e, name, p); err != nil {
+		return &EntryError{Name: e.name, Err: err}
+	}
+	p.Done = true
+	x.report(p)
+	return nil
+}
+
+// extractEntry implements extract, writing e to the path name.
+func (x *extractor) extractEntry(e entry, name string, p *Progress) error {
+	x.files++
+	if x.opts.maxFiles > 0 && x.files > x.opts.maxFiles {
+		return ErrTooManyFiles
+	}
+	target, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	x.report(p)
+	if e.mode.IsDir() {
+		return x.mkdirAll(target)
+	}
+	if err := x.mkdirAll(filepath.Dir(target)); err != nil {
+		return err
+	}
+	if keep, err := x.keep(target, e); err != nil || keep {
+		p.Skipped = keep
+		return err
+	}
+
+	switch {
+	case e.hardlink != "":
+		return x.hardLink(target, e.hardlink)
+	case e.mode&fs.ModeSymlink != 0:
+		if err := x.checkLink(target, e.linkname); err != nil {
+			return err
+		}
+		if err := x.prepare(target); err != nil {
+			return err
+		}
+		return os.Symlink(filepath.FromSlash(e.linkname), target)
+	case e.mode.IsRegular():
+		if err := x.prepare(target); err != nil {
+			return err
+		}
+		return x.writeFile(target, e, p)
+	}
+	// Devices, pipes and sockets are not extracted.
+	return nil
+}
+
+// rename returns the path that the entry name is extracted to, after the
+// include and exclude patterns, WithStripComponents and WithRename, or
+// false if the entry is left out.
+func (x *extractor) rename(name string) (string, bool) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if len(x.opts.include) > 0 && !matchAny(x.opts.include, clean) || matchAny(x.opts.exclude, clean) {
+		return "", false
+	}
+	if x.opts.strip > 0 {
+		elems := strings.Split(clean, "/")
+		if len(elems) <= x.opts.strip {
+			return "", false
+		}
+		clean = strings.Join(elems[x.opts.strip:], "/")
+	}
+	if x.opts.rename != nil {
+		if clean = x.opts.rename(clean); clean == "" {
+			return "", false
+		}
+	}
+	return clean, true
+}
+
+// keep reports whether the existing file at target stays, as the conflict
+// policy decides. It returns ErrExists for ConflictError.
+func (x *extractor) keep(target string, e entry) (bool, error) {
+	if x.opts.conflict == ConflictOverwrite {
+		return false, nil
+	}
+	info, err := os.Lstat(target)
+	if err != nil || info.IsDir() {
+		return false, nil
+	}
+	switch x.opts.conflict {
+	case ConflictSkip:
+		return true, nil
+	case ConflictNewer:
+		return !e.modTime.After(info.ModTime()), nil
+	}
+	return false, ErrExists
+}
+
+// report passes p to the progress callback, if any.
+func (x *extractor) report(p *Progress) {
+	if x.opts.progress != nil {
+		p.Total = x.total
+		x.opts.progress(*p)
+	}
+}
+
+// path returns the destination of the entry name, or ErrUnsafePath if it
+// is absolute or leaves the destination.
+func (x *extractor) path(name string) (string, error) {
+	if unsafeName(name) {
+		return "", ErrUnsafePath
+	}
+	clean := filepath.FromSlash(path.Clean(strings.ReplaceAll(name, `\`, "/")))
+	if clean == "." {
+		return x.dest, nil
+	}
+	if !filepath.IsLocal(clean) {
+		return "", ErrUnsafePath
+	}
+	return filepath.Join(x.dest, clean), nil
+}
+
+// unsafeName reports whether the entry name is absolute or has more ".."
+// elements than it descends. Backslashes are treated as separators, as some
+// Windows tools write them.
+func unsafeName(name string) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(name, "/") || hasVolume(name) {
+		return true
+	}
+	clean := path.Clean(name)
+	return clean == ".." || strings.HasPrefix(clean, "../")
+}
+
+// hasVolume reports whether name starts with a drive letter such as "C:",
+// which would be absolute or drive-relative on Windows.
+func hasVolume(name string) bool {
+	return len(name) >= 2 && name[1] == ':' &&
+		(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z')
+}
+
+// checkLink rejects a symlink at target whose link is absolute, resolves
+// outside the destination from the link's directory on disk, or has ".."
+// after another element. Such a ".." would step back out of whatever the
+// preceding elements resolve to, which may itself be a symlink, so chains
+// of links could escape. Links that pass only go up from a directory that
+// is known to be inside the destination, then down.
+func (x *extractor) checkLink(target, linkname string) error {
+	linkname = strings.ReplaceAll(linkname, `\`, "/")
+	if linkname == "" || strings.HasPrefix(linkname, "/") || hasVolume(linkname) {
+		return ErrUnsafePath
+	}
+	down := false
+	for _, elem := range strings.Split(linkname, "/") {
+		switch elem {
+		case "", ".":
+		case "..":
+			if down {
+				return ErrUnsafePath
+			}
+		default:
+			down = true
+		}
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(x.dest, filepath.Join(dir, filepath.FromSlash(linkname)))
+	if err != nil || rel != "." && !filepath.IsLocal(rel) {
+		return ErrUnsafePath
+	}
+	return nil
+}
+
+// hardLink links target to the earlier entry linkname, which must be a
+// regular file within the destination.
+func (x *extractor) hardLink(target, linkname string) error {
+	src, err := x.path(linkname)
+	if err != nil {
+		return err
+	}
+	if err := x.checkInside(filepath.Dir(src)); err != nil {
+		return err
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("hard link to %s, which is not a regular file", linkname)
+	}
+	if err := x.prepare(target); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(target); err == nil {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+	return os.Link(src, target)
+}
+
+// prepare creates the parent directories of target and removes an existing
+// symlink at target so that it is not written through.
+func (x *extractor) prepare(target string) error {
+	if err := x.mkdirAll(filepath.Dir(target)); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return os.Remove(target)
+	}
+	return nil
+}
+
+// mkdirAll creates dir like os.MkdirAll, after checking that its nearest
+// existing ancestor does not lead outside the destination through a symlink.
+func (x *extractor) mkdirAll(dir string) error {
+	existing := dir
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	if err := x.checkInside(existing); err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, os.ModePerm)
+}
+
+// checkInside returns ErrUnsafePath if dir, with symlinks resolved, is not
+// within the destination.
+func (x *extractor) checkInside(dir string) error {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(x.dest, resolved)
+	if err != nil || rel != "." && !filepath.IsLocal(rel) {
+		return ErrUnsafePath
+	}
+	return nil
+}
+
+// writeFile writes the content of a regular file entry to target and sets
+// its modification time. A file that fails part way, e.g. at a limit, is
+// removed.
+func (x *extractor) writeFile(target string, e entry, p *Progress) error {
+	rc, err := e.open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, e.mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(&limitWriter{w: out, x: x, e: &e, p: p}, rc)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && !e.modTime.IsZero() {
+		err = os.Chtimes(target, e.modTime, e.modTime)
+	}
+	if err != nil {
+		os.Remove(target)
+	}
+	return err
+}
+
+// limitWriter enforces the size and ratio limits while an entry is
+// written, and reports the progress.
+type limitWriter struct {
+	w       io.Writer
+	x       *extractor
+	e       *entry
+	p       *Progress
+	written int64
+}
+
+// Write implements io.Writer.
+func (l *limitWriter) Write(p []byte) (int, error) {
+	l.written += int64(len(p))
+	l.x.total += int64(len(p))
+	opts := l.x.opts
+	if opts.maxSize > 0 && l.x.total > opts.maxSize {
+		return 0, ErrTooLarge
+	}
+	written, compressed := l.written, l.e.compressed
+	if compressed == 0 && l.x.stream != nil {
+		written, compressed = l.x.total, l.x.stream.n
+	}
+	if opts.maxRatio > 0 && compressed > 0 && float64(written)/float64(compressed) > opts.maxRatio {
+		return 0, ErrRatio
+	}
+	n, err := l.w.Write(p)
+	l.p.Written = l.written
+	l.x.report(l.p)
+	return n, err
+}
+
+// checkDeclared fails early if the sizes an archive declares already exceed
+// the limits. The actual sizes are checked again while writing. The sizes
+// are also reported as progress.
+func (x *extractor) checkDeclared(count int, size int64) error {
+	x.entries, x.totalSize = count, size
+	if x.opts.maxFiles > 0 && count > x.opts.maxFiles {
+		return fmt.Errorf("archive: %w: %d entries, the limit is %d", ErrTooManyFiles, count, x.opts.maxFiles)
+	}
+	if x.opts.maxSize > 0 && size > x.opts.maxSize {
+		return fmt.Errorf("archive: %w: %d bytes, the limit is %d", ErrTooLarge, size, x.opts.maxSize)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEntry is an entry of a test archive.
+type testEntry struct {
+	hdr     *tar.Header
+	content string
+}
+
+func dirEntry(name string) testEntry {
+	return testEntry{hdr: &tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755}}
+}
+
+func symlinkEntry(name, target string) testEntry {
+	return testEntry{hdr: &tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: target, Mode: 0777}}
+}
+
+func hardlinkEntry(name, target string) testEntry {
+	return testEntry{hdr: &tar.Header{Name: name, Typeflag: tar.TypeLink, Linkname: target}}
+}
+
+func fileEntry(name, content string) testEntry {
+	return testEntry{hdr: &tar.Header{Name: name, Typeflag: tar.TypeReg, Size: int64(len(content)), Mode: 0644}, content: content}
+}
+
+// makeTar returns a tar archive of entries.
+func makeTar(t *testing.T, entries ...testEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		if err := tw.WriteHeader(e.hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gzipped returns data compressed with gzip.
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// makeZip returns a ZIP archive of deflated files with the given names and
+// contents.
+func makeZip(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// extractDir returns an empty destination inside a parent directory, so
+// that escapes land in the parent where the test can see them.
+func extractDir(t *testing.T) (parent, dest string) {
+	parent = t.TempDir()
+	return parent, filepath.Join(parent, "dest")
+}
+
+// checkNoEscape fails if anything besides dest was created in parent.
+func checkNoEscape(t *testing.T, parent string) {
+	t.Helper()
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "dest" {
+			t.Errorf("%s was created outside the destination", e.Name())
+		}
+	}
+}
+
+func TestSymlinkChain(t *testing.T) {
+	// y resolves to the parent of dest through x, although "x/.." looks
+	// like it stays in dest.
+	for _, tail := range [][]testEntry{nil, {fileEntry("y/pwned", "x")}} {
+		parent, dest := extractDir(t)
+		entries := append([]testEntry{symlinkEntry("x", "."), symlinkEntry("y", "x/..")}, tail...)
+		err := ExtractReader(bytes.NewReader(makeTar(t, entries...)), dest)
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%d entries: got %v, want ErrUnsafePath", len(entries), err)
+		}
+		if _, err := os.Lstat(filepath.Join(dest, "y")); err == nil {
+			t.Errorf("%d entries: the escaping link y was created", len(entries))
+		}
+		checkNoEscape(t, parent)
+	}
+}
+
+func TestUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+	}{
+		{"absolute path", []testEntry{fileEntry("/pwned", "x")}},
+		{"parent", []testEntry{fileEntry("../pwned", "x")}},
+		{"parent after a directory", []testEntry{fileEntry("a/../../pwned", "x")}},
+		{"backslash parent", []testEntry{fileEntry(`..\pwned`, "x")}},
+		{"backslash parent after a directory", []testEntry{fileEntry(`a\..\..\pwned`, "x")}},
+		{"drive letter", []testEntry{fileEntry(`C:\pwned`, "x")}},
+		{"absolute symlink", []testEntry{symlinkEntry("l", "/tmp")}},
+		{"symlink to the parent", []testEntry{symlinkEntry("l", "..")}},
+		{"symlink out of a directory", []testEntry{dirEntry("a/"), symlinkEntry("a/l", "../../pwned")}},
+		{"backslash symlink", []testEntry{symlinkEntry("l", `..\pwned`)}},
+		{"symlink chain", []testEntry{symlinkEntry("x", "."), symlinkEntry("y", "x/.."), fileEntry("y/pwned", "x")}},
+		{"hard link to the parent", []testEntry{hardlinkEntry("h", "../pwned")}},
+		{"absolute hard link", []testEntry{hardlinkEntry("h", "/etc/hostname")}},
+		{"hard link through a symlink", []testEntry{symlinkEntry("x", "."), symlinkEntry("y", "x/.."), hardlinkEntry("h", "y/pwned")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, dest := extractDir(t)
+			if err := os.WriteFile(filepath.Join(parent, "pwned"), []byte("outside"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := ExtractReader(bytes.NewReader(makeTar(t, tt.entries...)), dest)
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("got %v, want ErrUnsafePath", err)
+			}
+			if data, err := os.ReadFile(filepath.Join(parent, "pwned")); err != nil || string(data) != "outside" {
+				t.Errorf("the file outside the destination was changed: %q, %v", data, err)
+			}
+			if _, err := os.Lstat(filepath.Join(dest, "h")); err == nil {
+				t.Errorf("the hard link h was created")
+			}
+			entries, err := os.ReadDir(parent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if e.Name() != "dest" && e.Name() != "pwned" {
+					t.Errorf("%s was created outside the destination", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	zeros := strings.Repeat("\x00", 1<<20)
+	threeFiles := []testEntry{fileEntry("a", "1"), fileEntry("b", "2"), fileEntry("c", "3")}
+	tests := []struct {
+		name   string
+		zip    bool
+		data   []byte
+		opt    Option
+		want   error
+		within Option // a limit the archive does not exceed
+	}{
+		{"files", false, makeTar(t, threeFiles...), WithMaxFiles(2), ErrTooManyFiles, WithMaxFiles(3)},
+		{"zip files", true, makeZip(t, [2]string{"a", "1"}, [2]string{"b", "2"}, [2]string{"c", "3"}), WithMaxFiles(2), ErrTooManyFiles, WithMaxFiles(3)},
+		{"size", false, makeTar(t, fileEntry("a", "0123456789"), fileEntry("b", "0123456789")), WithMaxSize(15), ErrTooLarge, WithMaxSize(20)},
+		{"zip size", true, makeZip(t, [2]string{"a", "0123456789"}, [2]string{"b", "0123456789"}), WithMaxSize(15), ErrTooLarge, WithMaxSize(20)},
+		{"ratio", false, gzipped(t, makeTar(t, fileEntry("zeros", zeros))), WithMaxRatio(100), ErrRatio, WithMaxRatio(10000)},
+		{"zip ratio", true, makeZip(t, [2]string{"zeros", zeros}), WithMaxRatio(100), ErrRatio, WithMaxRatio(10000)},
+	}
+	extract := func(zipped bool, data []byte, dest string, opt Option) error {
+		if zipped {
+			return UnzipReader(bytes.NewReader(data), int64(len(data)), dest, opt)
+		}
+		return ExtractReader(bytes.NewReader(data), dest, opt)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, dest := extractDir(t)
+			if err := extract(tt.zip, tt.data, dest, tt.opt); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(dest, "zeros")); err == nil {
+				t.Errorf("the file over the limit was kept")
+			}
+			if err := extract(tt.zip, tt.data, t.TempDir(), tt.within); err != nil {
+				t.Errorf("within the limit: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnsafeEntriesWithOptions(t *testing.T) {
+	// Stripping "/x/" or "x/../../" would leave a safe name; the entries
+	// must fail all the same.
+	names := []string{"/x/pwned", "x/../../pwned", `C:\x\pwned`}
+	opts := map[string][]Option{
+		"none":    nil,
+		"strip":   {WithStripComponents(1)},
+		"include": {WithInclude("*"), WithExclude("nothing")},
+		"rename":  {WithRename(func(name string) string { return "safe" })},
+	}
+	for optName, opt := range opts {
+		for _, name := range names {
+			parent, dest := extractDir(t)
+			err := ExtractReader(bytes.NewReader(makeTar(t, fileEntry(name, "x"))), dest, opt...)
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("%s with %s: got %v, want ErrUnsafePath", name, optName, err)
+			}
+			checkNoEscape(t, parent)
+		}
+	}
+}
+
+func TestDeclaredSizeOverflow(t *testing.T) {
+	// The declared sizes add up to more than math.MaxInt64, which must not
+	// wrap around to a size within the limit.
+	tests := []struct {
+		name  string
+		sizes []uint64
+	}{
+		{"sum", []uint64{math.MaxInt64, math.MaxInt64, 2}},
+		{"single", []uint64{math.MaxUint64}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for i, size := range tt.sizes {
+				hdr := &zip.FileHeader{Name: fmt.Sprint(i), Method: zip.Store, UncompressedSize64: size, CompressedSize64: 1}
+				w, err := zw.CreateRaw(hdr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte("x"))
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			_, dest := extractDir(t)
+			err := UnzipReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), dest, WithMaxSize(100))
+			if !errors.Is(err, ErrTooLarge) {
+				t.Errorf("got %v, want ErrTooLarge", err)
+			}
+			if entries, _ := os.ReadDir(dest); len(entries) > 0 {
+				t.Errorf("%d entries were extracted", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Format is an archive format.
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatZip
+	FormatTar
+	FormatTarGz
+	FormatTarZst
+	FormatTarXz
+	FormatTarBz2 // extraction only
+)
+
+// formats lists the name and file extensions of each format.
+var formats = map[Format]struct {
+	name string
+	exts []string
+}{
+	FormatZip:    {"zip", []string{".zip"}},
+	FormatTar:    {"tar", []string{".tar"}},
+	FormatTarGz:  {"tar.gz", []string{".tar.gz", ".tgz"}},
+	FormatTarZst: {"tar.zst", []string{".tar.zst", ".tzst"}},
+	FormatTarXz:  {"tar.xz", []string{".tar.xz", ".txz"}},
+	FormatTarBz2: {"tar.bz2", []string{".tar.bz2", ".tbz2", ".tbz"}},
+}
+
+// String returns the name of the format, e.g. "tar.gz".
+func (f Format) String() string {
+	if info, ok := formats[f]; ok {
+		return info.name
+	}
+	return "unknown"
+}
+
+// WithFormat sets the format of the archive instead of detecting it. Create
+// otherwise uses the extension of dst and Extract the content of src.
+func WithFormat(f Format) Option {
+	return func(o *options) {
+		o.format = f
+	}
+}
+
+// FormatFromName returns the format that the extension of name stands
+// for, such as FormatTarGz for "dist.tgz", or FormatUnknown.
+func FormatFromName(name string) Format {
+	name = strings.ToLower(name)
+	for f, info := range formats {
+		for _, ext := range info.exts {
+			if strings.HasSuffix(name, ext) {
+				return f
+			}
+		}
+	}
+	return FormatUnknown
+}
+
+// Detect returns the format of the archive at path from its first bytes.
+// Tar archives without a ustar header, as written by very old tools, are
+// recognised by the ".tar" extension.
+func Detect(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FormatUnknown, err
+	}
+	defer f.Close()
+	return detectFile(f, path)
+}
+
+// detectFile implements Detect for an open file.
+func detectFile(f io.ReaderAt, name string) (Format, error) {
+	header := make([]byte, 512)
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return FormatUnknown, err
+	}
+	format := detect(header[:n])
+	if format == FormatUnknown && FormatFromName(name) == FormatTar {
+		format = FormatTar
+	}
+	return format, nil
+}
+
+// detect returns the format indicated by the magic bytes at the start of
+// an archive. Compressed streams are assumed to hold a tar archive.
+func detect(header []byte) Format {
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return FormatZip
+	case bytes.HasPrefix(header, []byte("\x1f\x8b")):
+		return FormatTarGz
+	case bytes.HasPrefix(header, []byte("\x28\xb5\x2f\xfd")):
+		return FormatTarZst
+	case bytes.HasPrefix(header, []byte("\xfd7zXZ\x00")):
+		return FormatTarXz
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return FormatTarBz2
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return FormatTar
+	}
+	return FormatUnknown
+}
+
+// Extract extracts the archive src to the dest directory, with the same
+// safety rules and options as Unzip. The format is detected from the
+// content of src; see Detect. Hard links in tar archives must point to a
+// regular file extracted before them.
+func Extract(src, dest string, opts ...Option) error {
+	o := newOptions(opts)
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	format := o.format
+	if format == FormatUnknown {
+		if format, err = detectFile(f, src); err != nil {
+			return err
+		}
+	}
+	switch format {
+	case FormatUnknown:
+		return fmt.Errorf("archive: %s: %w", src, ErrFormat)
+	case FormatZip:
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		r, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return fmt.Errorf("archive: %s: %w", src, err)
+		}
+		return unzip(r, dest, o)
+	}
+
+	return extractTar(f, format, dest, o)
+}
+
+// ExtractReader extracts a tar archive read from r to the dest directory,
+// like Extract, without needing a file. The compression is detected from
+// the first bytes of r; plain tar archives without a ustar header need
+// WithFormat(FormatTar). ZIP archives cannot be read as a stream, as their
+// index is at the end; use UnzipReader.
+func ExtractReader(r io.Reader, dest string, opts ...Option) error {
+	o := newOptions(opts)
+	br := bufio.NewReader(r)
+	format := o.format
+	if format == FormatUnknown {
+		header, err := br.Peek(512)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		format = detect(header)
+	}
+	switch format {
+	case FormatUnknown:
+		return fmt.Errorf("archive: %w", ErrFormat)
+	case FormatZip:
+		return errors.New("archive: ZIP archives cannot be extracted from a stream; use UnzipReader")
+	}
+	return extractTar(br, format, dest, o)
+}
+
+// extractTar extracts the tar archive in r, compressed as format says.
+func extractTar(r io.Reader, format Format, dest string, opts *options) error {
+	var stream *countReader
+	if format != FormatTar {
+		stream = &countReader{r: r}
+		r = stream
+	}
+	dr, err := decompress(r, format)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	defer dr.Close()
+	return untar(dr, dest, opts, stream)
+}
+
+// Create writes the contents of the srcDir directory to a new archive at
+// dst, like Zip. The format follows the extension of dst, e.g. ".tar.zst",
+// unless WithFormat is given; tar.bz2 archives cannot be created. Tar
+// archives keep owners unless WithModTime is given, and files that are hard
+// linked to each other are stored once, as hard links to the first of them.
+func Create(srcDir, dst string, opts ...Option) error {
+	o := newOptions(opts)
+	format := o.format
+	if format == FormatUnknown {
+		format = FormatFromName(dst)
+	}
+	switch format {
+	case FormatUnknown:
+		return fmt.Errorf("archive: %s: %w", dst, ErrFormat)
+	case FormatTarBz2:
+		return fmt.Errorf("archive: %s: creating %s archives is not supported", dst, format)
+	}
+	return create(srcDir, dst, format, o)
+}
+
+// decompress returns a reader for the tar stream in r.
+func decompress(r io.Reader, format Format) (io.ReadCloser, error) {
+	switch format {
+	case FormatTarGz:
+		return gzip.NewReader(r)
+	case FormatTarZst:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case FormatTarXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case FormatTarBz2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// compress returns a writer that compresses a tar stream into w. Closing
+// it flushes the compressor but does not close w.
+func compress(w io.Writer, format Format) (io.WriteCloser, error) {
+	switch format {
+	case FormatTarGz:
+		return gzip.NewWriter(w), nil
+	case FormatTarZst:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case FormatTarXz:
+		return xz.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// nopWriteCloser adds a Close method that does nothing to a writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close implements io.Closer.
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// countReader counts the bytes read from a compressed stream, for the
+// compression ratio limit.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implements io.Reader.
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
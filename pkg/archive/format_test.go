@@ -0,0 +1,169 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// bzip2Tar is a tar.bz2 archive of dir/ and dir/a.txt, which holds
+// "hello bzip2\n". Go has no bzip2 writer, so it was made with Python's
+// tarfile and bz2 modules.
+const bzip2Tar = "QlpoOTFBWSZTWY6cax4AAIv7gMqQBABAAf+AAIR2ZN5QCAggAHQSimgmJiPSPFMAm9QSVANANNAAAPupm0KFICWNJCHN5HMnCmB4mGCEPAOfXGVY+kEoFBCEXCDjHe+26Mn+aXOssmWapQ+wPEW2Vtjfk5kKdDMf+jBniIHYu5IpwoSEdONY8A=="
+
+// checkTree fails unless root holds the files of sampleTree.
+func checkTree(t *testing.T, root string) {
+	t.Helper()
+	for name, content := range sampleTree {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			if info, err := os.Stat(p); err != nil || !info.IsDir() {
+				t.Errorf("directory %s is missing: %v", name, err)
+			}
+			continue
+		}
+		if data, err := os.ReadFile(p); err != nil || string(data) != content {
+			t.Errorf("%s holds %q, %v; want %q", name, data, err, content)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, sampleTree)
+	tests := []struct {
+		name   string
+		format Format
+	}{
+		{"out.zip", FormatZip},
+		{"out.tar", FormatTar},
+		{"out.tar.gz", FormatTarGz},
+		{"out.tgz", FormatTarGz},
+		{"out.tar.zst", FormatTarZst},
+		{"out.tar.xz", FormatTarXz},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatFromName(tt.name); got != tt.format {
+				t.Errorf("FormatFromName = %v, want %v", got, tt.format)
+			}
+			dst := filepath.Join(t.TempDir(), tt.name)
+			if err := Create(src, dst); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := Detect(dst); err != nil || got != tt.format {
+				t.Errorf("Detect = %v, %v; want %v", got, err, tt.format)
+			}
+			dest := t.TempDir()
+			if err := Extract(dst, dest); err != nil {
+				t.Fatal(err)
+			}
+			checkTree(t, dest)
+
+			// A misleading name does not matter, as Extract reads the content.
+			renamed := filepath.Join(t.TempDir(), "archive.bin")
+			if err := os.Rename(dst, renamed); err != nil {
+				t.Fatal(err)
+			}
+			dest = t.TempDir()
+			if err := Extract(renamed, dest); err != nil {
+				t.Fatal(err)
+			}
+			checkTree(t, dest)
+		})
+	}
+}
+
+func TestExtractBzip2(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(bzip2Tar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "a.tbz")
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Detect(src); got != FormatTarBz2 {
+		t.Errorf("Detect = %v, want tar.bz2", got)
+	}
+	for _, extract := range []func(dest string) error{
+		func(dest string) error { return Extract(src, dest) },
+		func(dest string) error { return ExtractReader(bytes.NewReader(data), dest) },
+	} {
+		dest := t.TempDir()
+		if err := extract(dest); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := os.ReadFile(filepath.Join(dest, "dir", "a.txt")); err != nil || string(got) != "hello bzip2\n" {
+			t.Errorf("dir/a.txt holds %q, %v", got, err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	tarData := makeTar(t, fileEntry("a", "1"))
+	// Tar archives from very old tools have no ustar magic.
+	oldTar := append([]byte(nil), tarData...)
+	copy(oldTar[257:265], make([]byte, 8))
+	copy(oldTar[148:156], "        ")
+	var sum int
+	for _, b := range oldTar[:512] {
+		sum += int(b)
+	}
+	copy(oldTar[148:156], fmt.Sprintf("%06o\x00 ", sum))
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{"a.zip", makeZip(t, [2]string{"a", "1"}), FormatZip},
+		{"empty.zip", makeZip(t), FormatZip},
+		{"a.tar", tarData, FormatTar},
+		{"a.bin", tarData, FormatTar},
+		{"old.tar", oldTar, FormatTar},
+		{"old.bin", oldTar, FormatUnknown},
+		{"a.tar.gz", gzipped(t, tarData), FormatTarGz},
+		{"a.txt", []byte("hello"), FormatUnknown},
+		{"empty", nil, FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := Detect(path); err != nil || got != tt.want {
+				t.Errorf("Detect = %v, %v; want %v", got, err, tt.want)
+			}
+			err := Extract(path, t.TempDir())
+			if tt.want == FormatUnknown && !errors.Is(err, ErrFormat) {
+				t.Errorf("Extract: got %v, want ErrFormat", err)
+			}
+			if tt.want != FormatUnknown && err != nil {
+				t.Errorf("Extract: %v", err)
+			}
+		})
+	}
+	if _, err := Detect(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Detect of a missing file: %v", err)
+	}
+}
+
+func TestExtractWithFormat(t *testing.T) {
+	data := gzipped(t, makeTar(t, fileEntry("a", "1")))
+	src := filepath.Join(t.TempDir(), "a.tar.gz")
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Extract(src, t.TempDir(), WithFormat(FormatTarXz)); err == nil {
+		t.Error("WithFormat(FormatTarXz) extracted a gzip stream")
+	}
+	if err := Extract(src, t.TempDir(), WithFormat(FormatTarGz)); err != nil {
+		t.Errorf("WithFormat(FormatTarGz): %v", err)
+	}
+}
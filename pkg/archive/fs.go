@@ -0,0 +1,295 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FS is a read-only view of the files in an archive, for reading single
+// entries without extracting the archive. Entries whose path is absolute
+// or contains ".." are left out. Symlinks are not followed; reading one
+// returns its target for ZIP archives and nothing for tar archives.
+//
+// Files in compressed tar archives are read by decompressing the archive
+// up to them, so ZIP and uncompressed tar archives suit random access best.
+type FS struct {
+	fsys   fs.FS
+	format Format
+	closer io.Closer
+}
+
+// NewFS returns a view of the archive of the given size read from r. The
+// format is detected from its first bytes unless WithFormat is given. r
+// must stay readable while the FS is used.
+func NewFS(r io.ReaderAt, size int64, opts ...Option) (*FS, error) {
+	o := newOptions(opts)
+	format := o.format
+	if format == FormatUnknown {
+		var err error
+		if format, err = detectFile(r, ""); err != nil {
+			return nil, err
+		}
+	}
+	switch format {
+	case FormatUnknown:
+		return nil, fmt.Errorf("archive: %w", ErrFormat)
+	case FormatZip:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, fmt.Errorf("archive: %w", err)
+		}
+		return &FS{fsys: zr, format: format}, nil
+	}
+	t, err := newTarFS(r, size, format)
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	return &FS{fsys: t, format: format}, nil
+}
+
+// OpenFS returns a view of the archive at path, like NewFS. The FS must be
+// closed after use.
+func OpenFS(path string, opts ...Option) (*FS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	o := newOptions(opts)
+	if o.format == FormatUnknown {
+		format, err := detectFile(f, path)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		opts = append(opts, WithFormat(format))
+	}
+	fsys, err := NewFS(f, info.Size(), opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	fsys.closer = f
+	return fsys, nil
+}
+
+// Open implements fs.FS.
+func (f *FS) Open(name string) (fs.File, error) {
+	return f.fsys.Open(name)
+}
+
+// Format returns the format of the archive.
+func (f *FS) Format() Format {
+	return f.format
+}
+
+// Close closes the archive file opened by OpenFS. It does nothing for an
+// FS from NewFS.
+func (f *FS) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+// tarFS is an index of a tar archive.
+type tarFS struct {
+	r       io.ReaderAt
+	size    int64
+	format  Format
+	entries map[string]*tarEntry
+}
+
+// tarEntry is a file or directory of a tarFS. Directories that only appear
+// in the paths of other entries have no index.
+type tarEntry struct {
+	hdr      *tar.Header
+	index    int   // position in the archive, or -1
+	offset   int64 // start of the data in the uncompressed archive
+	direct   bool  // whether the data can be read at offset
+	children []fs.DirEntry
+}
+
+// newTarFS reads the tar archive in r once to index its entries.
+func newTarFS(r io.ReaderAt, size int64, format Format) (*tarFS, error) {
+	t := &tarFS{r: r, size: size, format: format, entries: make(map[string]*tarEntry)}
+	t.entries["."] = &tarEntry{hdr: &tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0555}, index: -1}
+
+	dr, err := decompress(io.NewSectionReader(r, 0, size), format)
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+	cr := &countReader{r: dr}
+	tr := tar.NewReader(cr)
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset := cr.n
+		// Reading the data measures how much is stored: sparse files take
+		// fewer bytes than their size and can only be read through a
+		// tar.Reader.
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return nil, err
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag == tar.TypeXGlobalHeader || name == "." || !fs.ValidPath(name) {
+			continue
+		}
+		e := &tarEntry{
+			hdr:    hdr,
+			index:  i,
+			offset: offset,
+			direct: format == FormatTar && cr.n-offset == hdr.Size,
+		}
+		if hdr.Typeflag == tar.TypeLink {
+			target, ok := t.entries[path.Clean(hdr.Linkname)]
+			if !ok || !target.hdr.FileInfo().Mode().IsRegular() {
+				continue
+			}
+			linked := *target.hdr
+			linked.Name = hdr.Name
+			e = &tarEntry{hdr: &linked, index: target.index, offset: target.offset, direct: target.direct}
+		}
+		t.addParents(name)
+		t.entries[name] = e
+	}
+
+	for name, e := range t.entries {
+		if name == "." {
+			continue
+		}
+		parent := t.entries[path.Dir(name)]
+		parent.children = append(parent.children, fs.FileInfoToDirEntry(e.hdr.FileInfo()))
+	}
+	for _, e := range t.entries {
+		sort.Slice(e.children, func(i, j int) bool {
+			return e.children[i].Name() < e.children[j].Name()
+		})
+	}
+	return t, nil
+}
+
+// addParents adds the missing parent directories of name.
+func (t *tarFS) addParents(name string) {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := t.entries[dir]; ok {
+			return
+		}
+		t.entries[dir] = &tarEntry{hdr: &tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0555}, index: -1}
+	}
+}
+
+// Open implements fs.FS.
+func (t *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := t.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	info := e.hdr.FileInfo()
+	if info.IsDir() {
+		return &tarDir{info: info, entries: e.children}, nil
+	}
+	if !info.Mode().IsRegular() {
+		return &tarFile{info: info, r: strings.NewReader("")}, nil
+	}
+	if e.direct {
+		return &tarFile{info: info, r: io.NewSectionReader(t.r, e.offset, e.hdr.Size)}, nil
+	}
+
+	dr, err := decompress(io.NewSectionReader(t.r, 0, t.size), t.format)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	tr := tar.NewReader(dr)
+	for i := 0; i <= e.index; i++ {
+		if _, err := tr.Next(); err != nil {
+			dr.Close()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return &tarFile{info: info, r: tr, closer: dr}, nil
+}
+
+// tarFile is an open file of a tarFS.
+type tarFile struct {
+	info   fs.FileInfo
+	r      io.Reader
+	closer io.Closer
+}
+
+// Stat implements fs.File.
+func (f *tarFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Read implements fs.File.
+func (f *tarFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+// Close implements fs.File.
+func (f *tarFile) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+// tarDir is an open directory of a tarFS.
+type tarDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	pos     int
+}
+
+// Stat implements fs.File.
+func (d *tarDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read implements fs.File.
+func (d *tarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// Close implements fs.File.
+func (d *tarDir) Close() error {
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.pos:]
+	if n <= 0 {
+		d.pos = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.pos += n
+	return rest[:n], nil
+}
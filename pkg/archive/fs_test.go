@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// fsArchives returns sampleTree as an archive in each format that FS reads.
+func fsArchives(t *testing.T) map[string][]byte {
+	t.Helper()
+	src := t.TempDir()
+	writeTree(t, src, sampleTree)
+	archives := make(map[string][]byte)
+	for _, name := range []string{"a.zip", "a.tar", "a.tar.gz", "a.tar.zst", "a.tar.xz"} {
+		dst := filepath.Join(t.TempDir(), name)
+		if err := Create(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives[name] = data
+	}
+	return archives
+}
+
+func TestFS(t *testing.T) {
+	for name, data := range fsArchives(t) {
+		t.Run(name, func(t *testing.T) {
+			fsys, err := NewFS(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fsys.Close()
+			if want := FormatFromName(name); fsys.Format() != want {
+				t.Errorf("Format = %v, want %v", fsys.Format(), want)
+			}
+			if err := fstest.TestFS(fsys, ".git/config", "a.go", "a.tmp", "docs/readme.md", "docs/img/logo.png", "sub/b.go"); err != nil {
+				t.Fatal(err)
+			}
+			for file, content := range sampleTree {
+				if strings.HasSuffix(file, "/") {
+					continue
+				}
+				if got, err := fs.ReadFile(fsys, file); err != nil || string(got) != content {
+					t.Errorf("%s holds %q, %v; want %q", file, got, err, content)
+				}
+			}
+			if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Open(missing): %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenFS(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range fsArchives(t) {
+		t.Run(name, func(t *testing.T) {
+			// The extension is not needed to detect the format.
+			path := filepath.Join(dir, name+".bin")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			fsys, err := OpenFS(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := fs.ReadFile(fsys, "sub/b.go")
+			if err != nil || string(got) != "package b" {
+				t.Errorf("sub/b.go holds %q, %v", got, err)
+			}
+			if err := fsys.Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		})
+	}
+	garbage := filepath.Join(dir, "garbage")
+	os.WriteFile(garbage, []byte("not an archive"), 0644)
+	if _, err := OpenFS(garbage); !errors.Is(err, ErrFormat) {
+		t.Errorf("OpenFS(garbage): got %v, want ErrFormat", err)
+	}
+	if _, err := OpenFS(filepath.Join(dir, "missing.zip")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenFS(missing): got %v", err)
+	}
+}
+
+func TestFSSkipsUnsafeNames(t *testing.T) {
+	data := makeTar(t,
+		fileEntry("/abs", "x"), fileEntry("../up", "x"), fileEntry("ok/file", "fine"),
+		symlinkEntry("ok/link", "file"), hardlinkEntry("ok/hard", "ok/file"), hardlinkEntry("ok/dangling", "missing"))
+	fsys, err := NewFS(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "ok/file", "ok/hard", "ok/link"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "ok" {
+		t.Errorf("root holds %v, %v; want only ok", entries, err)
+	}
+	if got, err := fs.ReadFile(fsys, "ok/hard"); err != nil || string(got) != "fine" {
+		t.Errorf("ok/hard holds %q, %v", got, err)
+	}
+	if _, err := fsys.Open("ok/dangling"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("dangling hard link: %v", err)
+	}
+}
+
+func TestExtractReader(t *testing.T) {
+	archives := fsArchives(t)
+	for name, data := range archives {
+		t.Run(name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := ExtractReader(io.MultiReader(bytes.NewReader(data)), dest)
+			if name == "a.zip" {
+				if err == nil || !strings.Contains(err.Error(), "use UnzipReader") {
+					t.Errorf("got %v, want a hint to use UnzipReader", err)
+				}
+				err = UnzipReader(bytes.NewReader(data), int64(len(data)), dest)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTree(t, dest)
+		})
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		opts []Option
+		want error
+	}{
+		{"garbage", []byte("not an archive"), nil, ErrFormat},
+		{"empty", nil, nil, ErrFormat},
+		{"short tar", makeTar(t)[:100], nil, ErrFormat},
+		{"forced format", []byte("not an archive"), []Option{WithFormat(FormatTarGz)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ExtractReader(bytes.NewReader(tt.data), t.TempDir(), tt.opts...)
+			if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if err := UnzipReader(bytes.NewReader([]byte("junk")), 4, t.TempDir()); err == nil {
+		t.Error("UnzipReader of junk did not fail")
+	}
+}
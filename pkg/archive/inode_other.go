@@ -0,0 +1,11 @@
+//go:build !unix
+
+package archive
+
+import "io/fs"
+
+// fileInode reports no inode on platforms where hard links are not detected,
+// so that every file is stored with its content.
+func fileInode(info fs.FileInfo) (inode, bool) {
+	return inode{}, false
+}
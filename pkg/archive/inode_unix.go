@@ -0,0 +1,17 @@
+//go:build unix
+
+package archive
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileInode returns the inode of a file that has more than one hard link.
+func fileInode(info fs.FileInfo) (inode, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink < 2 {
+		return inode{}, false
+	}
+	return inode{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
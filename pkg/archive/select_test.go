@@ -0,0 +1,197 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readTree returns the regular files below root and their contents, by
+// '/'-separated path.
+func readTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestStripAndRename(t *testing.T) {
+	data := makeTar(t,
+		dirEntry("top/"),
+		fileEntry("top/a.txt", "a"),
+		fileEntry("top/sub/b.txt", "b"),
+		fileEntry("top/skip.tmp", "tmp"),
+		hardlinkEntry("top/h", "top/a.txt"),
+	)
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]string
+		err  error
+	}{
+		{"none", nil,
+			map[string]string{"top/a.txt": "a", "top/sub/b.txt": "b", "top/skip.tmp": "tmp", "top/h": "a"}, nil},
+		{"strip 1", []Option{WithStripComponents(1)},
+			map[string]string{"a.txt": "a", "sub/b.txt": "b", "skip.tmp": "tmp", "h": "a"}, nil},
+		{"strip 2", []Option{WithStripComponents(2)},
+			map[string]string{"b.txt": "b"}, nil},
+		{"strip everything", []Option{WithStripComponents(5)},
+			map[string]string{}, nil},
+		{"rename", []Option{WithRename(func(name string) string { return "new/" + name })},
+			map[string]string{"new/top/a.txt": "a", "new/top/sub/b.txt": "b", "new/top/skip.tmp": "tmp", "new/top/h": "a"}, nil},
+		{"rename after strip", []Option{WithStripComponents(1), WithRename(func(name string) string {
+			if strings.HasSuffix(name, ".tmp") {
+				return ""
+			}
+			return strings.ToUpper(name)
+		})},
+			map[string]string{"A.TXT": "a", "SUB/B.TXT": "b", "H": "a"}, nil},
+		{"rename out of the destination", []Option{WithRename(func(name string) string { return "../" + name })},
+			map[string]string{}, ErrUnsafePath},
+		{"include with strip", []Option{WithInclude("top/sub"), WithStripComponents(1)},
+			map[string]string{"sub/b.txt": "b"}, nil},
+		{"exclude", []Option{WithExclude("*.tmp", "sub")},
+			map[string]string{"top/a.txt": "a", "top/h": "a"}, nil},
+		{"hard link with its target", []Option{WithInclude("a.txt", "h")},
+			map[string]string{"top/a.txt": "a", "top/h": "a"}, nil},
+		{"hard link without its target", []Option{WithInclude("h")},
+			map[string]string{}, nil},
+		{"hard link with an excluded target", []Option{WithExclude("a.txt")},
+			map[string]string{"top/sub/b.txt": "b", "top/skip.tmp": "tmp"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, dest := extractDir(t)
+			err := ExtractReader(bytes.NewReader(data), dest, tt.opts...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if got := readTree(t, dest); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			checkNoEscape(t, parent)
+		})
+	}
+}
+
+func TestConflict(t *testing.T) {
+	existing := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	older, newer := existing.Add(-time.Hour), existing.Add(time.Hour)
+	entry := func(modTime time.Time) []byte {
+		return makeTar(t,
+			dirEntry("d/"),
+			fileEntry("d/new", "added"),
+			testEntry{hdr: &tar.Header{Name: "a", Typeflag: tar.TypeReg, Size: 3, Mode: 0644, ModTime: modTime}, content: "new"},
+		)
+	}
+	tests := []struct {
+		name     string
+		conflict Conflict
+		modTime  time.Time
+		want     string
+		skipped  bool
+		err      error
+	}{
+		{"overwrite", ConflictOverwrite, older, "new", false, nil},
+		{"skip", ConflictSkip, newer, "old", true, nil},
+		{"newer entry", ConflictNewer, newer, "new", false, nil},
+		{"older entry", ConflictNewer, older, "old", true, nil},
+		{"same time", ConflictNewer, existing, "old", true, nil},
+		{"error", ConflictError, newer, "old", false, ErrExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			writeTree(t, dest, map[string]string{"a": "old", "d/keep": "kept"})
+			if err := os.Chtimes(filepath.Join(dest, "a"), existing, existing); err != nil {
+				t.Fatal(err)
+			}
+			var skipped []string
+			progress := WithProgress(func(p Progress) {
+				if p.Skipped {
+					skipped = append(skipped, p.Name)
+				}
+			})
+			err := ExtractReader(bytes.NewReader(entry(tt.modTime)), dest, WithConflict(tt.conflict), progress)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			var entryErr *EntryError
+			if tt.err != nil && (!errors.As(err, &entryErr) || entryErr.Name != "a") {
+				t.Errorf("error %v does not name the entry", err)
+			}
+			want := map[string]string{"a": tt.want, "d/keep": "kept", "d/new": "added"}
+			if got := readTree(t, dest); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			if (len(skipped) > 0) != tt.skipped || tt.skipped && skipped[0] != "a" {
+				t.Errorf("skipped %v, want skipped %v", skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	zipData := makeZip(t, [2]string{"a", "0123456789"}, [2]string{"d/", ""}, [2]string{"d/b", "01234"}, [2]string{"c.tmp", "x"})
+	tarData := makeTar(t, fileEntry("a", "0123456789"), dirEntry("d/"), fileEntry("d/b", "01234"), fileEntry("c.tmp", "x"))
+	tests := []struct {
+		name    string
+		extract func(dest string, opts ...Option) error
+		entries int
+		total   int64
+	}{
+		{"zip", func(dest string, opts ...Option) error {
+			return UnzipReader(bytes.NewReader(zipData), int64(len(zipData)), dest, opts...)
+		}, 4, 16},
+		{"tar", func(dest string, opts ...Option) error {
+			return ExtractReader(bytes.NewReader(tarData), dest, opts...)
+		}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			writeTree(t, dest, map[string]string{"d/b": "existing"})
+			var got []Progress
+			err := tt.extract(dest, WithExclude("*.tmp"), WithConflict(ConflictSkip), WithProgress(func(p Progress) {
+				got = append(got, p)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, size := tt.entries, tt.total
+			want := []Progress{
+				{Name: "a", Entry: 1, Entries: n, Size: 10, TotalSize: size},
+				{Name: "a", Entry: 1, Entries: n, Size: 10, TotalSize: size, Written: 10, Total: 10},
+				{Name: "a", Entry: 1, Entries: n, Size: 10, TotalSize: size, Written: 10, Total: 10, Done: true},
+				{Name: "d/", Entry: 2, Entries: n, TotalSize: size, Total: 10},
+				{Name: "d/", Entry: 2, Entries: n, TotalSize: size, Total: 10, Done: true},
+				{Name: "d/b", Entry: 3, Entries: n, Size: 5, TotalSize: size, Total: 10},
+				{Name: "d/b", Entry: 3, Entries: n, Size: 5, TotalSize: size, Total: 10, Skipped: true, Done: true},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// untar extracts the tar stream r to dest. stream counts the compressed
+// input for the ratio limit, or is nil for an uncompressed archive.
+func untar(r io.Reader, dest string, opts *options, stream *countReader) error {
+	x, err := newExtractor(dest, opts)
+	if err != nil {
+		return err
+	}
+	x.stream = stream
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("archive: %w", err)
+		}
+		e := entry{
+			name:    hdr.Name,
+			mode:    hdr.FileInfo().Mode(),
+			size:    hdr.Size,
+			modTime: hdr.ModTime,
+			open: func() (io.ReadCloser, error) {
+				return io.NopCloser(tr), nil
+			},
+		}
+		switch hdr.Typeflag {
+		case tar.TypeXGlobalHeader:
+			// PAX defaults for the following entries; tar.Reader applies
+			// the ones it knows.
+			continue
+		case tar.TypeSymlink:
+			e.linkname = hdr.Linkname
+		case tar.TypeLink:
+			e.hardlink = hdr.Linkname
+		}
+		if err := x.extract(e); err != nil {
+			return err
+		}
+	}
+}
+
+// writeCompressedTar writes sources to w as a tar archive compressed as
+// format requires.
+func writeCompressedTar(w io.Writer, format Format, sources []source, opts *options) error {
+	cw, err := compress(w, format)
+	if err != nil {
+		return err
+	}
+	if err := writeTar(cw, sources, opts); err != nil {
+		cw.Close()
+		return err
+	}
+	return cw.Close()
+}
+
+// inode identifies a file on disk, to find hard links.
+type inode struct {
+	dev, ino uint64
+}
+
+// writeTar writes sources to w as a tar archive.
+func writeTar(w io.Writer, sources []source, opts *options) error {
+	tw := tar.NewWriter(w)
+	links := make(map[inode]string)
+	for _, s := range sources {
+		if err := addTarEntry(tw, s, opts, links); err != nil {
+			return &EntryError{Name: s.name, Err: err}
+		}
+	}
+	return tw.Close()
+}
+
+// addTarEntry writes one source to tw. links maps the files that have more
+// than one link to the first entry written for them.
+func addTarEntry(tw *tar.Writer, s source, opts *options, links map[inode]string) error {
+	var target string
+	if s.info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(s.path)
+		if err != nil {
+			return err
+		}
+		target = filepath.ToSlash(link)
+	}
+	hdr, err := tar.FileInfoHeader(s.info, target)
+	if err != nil {
+		return err
+	}
+	hdr.Name = s.name
+	if id, ok := fileInode(s.info); ok && s.info.Mode().IsRegular() {
+		if first, seen := links[id]; seen {
+			hdr.Typeflag = tar.TypeLink
+			hdr.Linkname = first
+			hdr.Size = 0
+		} else {
+			links[id] = s.name
+		}
+	}
+	if !opts.modTime.IsZero() {
+		hdr.ModTime = opts.modTime
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		return nil
+	}
+	in, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(tw, in)
+	return err
+}
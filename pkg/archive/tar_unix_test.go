@@ -0,0 +1,106 @@
+//go:build unix
+
+package archive
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateHardLinks(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"a": "shared", "c": "other", "sub/": ""})
+	for _, name := range []string{"b", "sub/d"} {
+		if err := os.Link(filepath.Join(src, "a"), filepath.Join(src, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(t.TempDir(), "out.tar.gz")
+	if err := Create(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := OpenFS(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	want := map[string]struct {
+		typeflag byte
+		linkname string
+		size     int64
+	}{
+		"a":     {tar.TypeReg, "", 6},
+		"b":     {tar.TypeLink, "a", 0},
+		"c":     {tar.TypeReg, "", 5},
+		"sub/":  {tar.TypeDir, "", 0},
+		"sub/d": {tar.TypeLink, "a", 0},
+	}
+	f, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dr, err := decompress(f, FormatTarGz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(dr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, ok := want[hdr.Name]
+		if !ok {
+			t.Errorf("unexpected entry %s", hdr.Name)
+			continue
+		}
+		delete(want, hdr.Name)
+		if hdr.Typeflag != w.typeflag || hdr.Linkname != w.linkname || hdr.Size != w.size {
+			t.Errorf("%s: type %c link %q size %d, want type %c link %q size %d",
+				hdr.Name, hdr.Typeflag, hdr.Linkname, hdr.Size, w.typeflag, w.linkname, w.size)
+		}
+	}
+	for name := range want {
+		t.Errorf("entry %s is missing", name)
+	}
+
+	dest := t.TempDir()
+	if err := Extract(dst, dest); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.Stat(filepath.Join(dest, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b", "sub/d"} {
+		info, err := os.Stat(filepath.Join(dest, name))
+		if err != nil || !os.SameFile(first, info) {
+			t.Errorf("%s is not a hard link to a: %v", name, err)
+		}
+	}
+	if data, err := fs.ReadFile(fsys, "sub/d"); err != nil || string(data) != "shared" {
+		t.Errorf("reading the link through the FS: %q, %v", data, err)
+	}
+
+	// ZIP has no hard links, so each name gets a copy.
+	zipDst := filepath.Join(t.TempDir(), "out.zip")
+	if err := Zip(src, zipDst); err != nil {
+		t.Fatal(err)
+	}
+	dest = t.TempDir()
+	if err := Unzip(zipDst, dest); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "sub", "d")); err != nil || string(data) != "shared" {
+		t.Errorf("sub/d in the ZIP holds %q, %v", data, err)
+	}
+}
@@ -1,81 +1,185 @@
-// Package archive provides utilities for working with archive formats, currently supporting ZIP extraction.
+// Package archive provides utilities for working with archive formats: ZIP and tar, the latter
+// uncompressed or compressed with gzip, zstd, xz or bzip2.
 //
 // The package focuses on safe and reliable archive extraction with proper path handling
-// and directory structure preservation. It includes security measures to prevent
-// path traversal attacks and ensures proper file permissions are maintained.
+// and directory structure preservation. Entries are never written outside the
+// destination: absolute paths, ".." components and symlinks that point outside
+// are rejected with ErrUnsafePath. Limits on the total size, the number of
+// entries and the compression ratio protect against zip bombs.
 //
 // Current functionality:
-//   - ZIP archive extraction with full directory structure preservation
+//   - ZIP and tar archive extraction with full directory structure preservation
+//   - Format detection by magic bytes (see Detect)
+//   - Extraction from memory or streams, and an fs.FS view of an archive (see FS)
+//   - Selective extraction, conflict policies and progress reports (see Progress)
 //   - Safe path handling to prevent directory traversal attacks
+//   - Size, entry count and compression ratio limits (see Option)
 //   - Automatic parent directory creation
 //   - Proper file permission preservation
+//   - Reproducible ZIP and tar creation with include and exclude patterns
 //
 // Example usage:
 //
-//	err := archive.Unzip("archive.zip", "/path/to/extract")
+//	err := archive.Unzip("archive.zip", "/path/to/extract",
+//		archive.WithMaxSize(1<<30), archive.WithMaxFiles(10000), archive.WithMaxRatio(100))
 //	if err != nil {
 //		log.Fatal("Failed to extract archive:", err)
-//	}
-package archive
-
-import (
-	"archive/zip"
-	"io"
-	"os"
-	"path/filepath"
-)
-
-// Unzip extracts a ZIP archive from src to the dest directory.
-// All files and folders in the archive will be extracted, preserving the directory structure.
-// Returns an error if extraction fails.
-func Unzip(src, dest string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
-	}
-	defer r.Close()
-
-	// Ensure destination directory exists
-	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
-		return err
-	}
-
-	for _, f := range r.File {
-		fpath := filepath.Join(dest, f.Name)
-		
-		// Clean the path to handle any issues with path separators
-		fpath = filepath.Clean(fpath)
-		
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(fpath, os.ModePerm); err != nil {
-				return err
-			}
-			continue
-		}
-
-		// Ensure parent directory exists for files
-		if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			return err
-		}
-
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return err
-		}
-
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
+//	}
+//
+//	err = archive.Extract("release.tar.zst", "/path/to/extract", archive.WithMaxSize(1<<30))
+//
+//	err = archive.Zip("/path/to/project", "project.zip",
+//		archive.WithExclude(".git", "*.tmp"), archive.WithModTime(time.Unix(0, 0)))
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Unzip extracts a ZIP archive from src to the dest directory.
+// All files and folders in the archive will be extracted, preserving the directory structure.
+// Symlinks are created if their target stays within dest.
+// Returns an error if extraction fails; errors about a single entry are an *EntryError.
+func Unzip(src, dest string, opts ...Option) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return unzip(&r.Reader, dest, newOptions(opts))
+}
+
+// UnzipReader extracts the ZIP archive of the given size read from r to the
+// dest directory, like Unzip. Use it for archives in memory, e.g. with a
+// bytes.Reader, or embedded in a larger file.
+func UnzipReader(r io.ReaderAt, size int64, dest string, opts ...Option) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	return unzip(zr, dest, newOptions(opts))
+}
+
+// Zip writes the contents of the srcDir directory to a new ZIP archive at
+// dst, with paths relative to srcDir. Entries are added in lexical order
+// and keep their permissions; symlinks are stored as links. With
+// WithModTime the archive only depends on the file contents, names and
+// modes. dst may be inside srcDir and is not added to itself. If Zip fails,
+// dst is removed.
+func Zip(srcDir, dst string, opts ...Option) error {
+	return create(srcDir, dst, FormatZip, newOptions(opts))
+}
+
+// writeZip writes sources to w as a ZIP archive.
+func writeZip(w io.Writer, sources []source, opts *options) error {
+	zw := zip.NewWriter(w)
+	for _, s := range sources {
+		if err := addZipEntry(zw, s, opts); err != nil {
+			return &EntryError{Name: s.name, Err: err}
+		}
+	}
+	return zw.Close()
+}
+
+// addZipEntry writes one source to w.
+func addZipEntry(w *zip.Writer, s source, opts *options) error {
+	hdr, err := zip.FileInfoHeader(s.info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = s.name
+	if !opts.modTime.IsZero() {
+		hdr.Modified = opts.modTime
+	}
+	hdr.Method = zip.Deflate
+	if !s.info.Mode().IsRegular() || matchAny(opts.store, s.name) {
+		hdr.Method = zip.Store
+	}
+
+	out, err := w.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	switch {
+	case s.info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(s.path)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(out, filepath.ToSlash(target))
+		return err
+	case s.info.Mode().IsRegular():
+		in, err := os.Open(s.path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(out, in)
+		return err
+	}
+	return nil
+}
+
+// unzip extracts the files of r to dest.
+func unzip(r *zip.Reader, dest string, opts *options) error {
+	x, err := newExtractor(dest, opts)
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, f := range r.File {
+		// Declared sizes are untrusted; saturate instead of overflowing.
+		if n := declaredSize(f); n > math.MaxInt64-size {
+			size = math.MaxInt64
+		} else {
+			size += n
+		}
+	}
+	if err := x.checkDeclared(len(r.File), size); err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		e := entry{
+			name:       f.Name,
+			mode:       f.Mode(),
+			size:       declaredSize(f),
+			compressed: int64(min(f.CompressedSize64, math.MaxInt64)),
+			modTime:    f.Modified,
+			open:       f.Open,
+		}
+		if e.mode&fs.ModeSymlink != 0 {
+			if e.linkname, err = readLink(f); err != nil {
+				return &EntryError{Name: f.Name, Err: err}
+			}
+		}
+		if err := x.extract(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declaredSize returns the uncompressed size that f declares, limited to
+// math.MaxInt64.
+func declaredSize(f *zip.File) int64 {
+	return int64(min(f.UncompressedSize64, math.MaxInt64))
+}
+
+// readLink returns the target of a symlink entry, which ZIP stores as the content.
+func readLink(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	// Link targets are short; anything longer is not a real link.
+	target, err := io.ReadAll(io.LimitReader(rc, 4096))
+	return string(target), err
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultPerm is the mode of files created by WriteFile.
+const defaultPerm fs.FileMode = 0644
+
+// WithBackup makes Save and WriteFile keep the previous version of a file
+// next to it, with ".bak" appended to its name.
+func WithBackup() Option {
+	return func(l *Loader) {
+		l.backup = true
+	}
+}
+
+// WriteFile replaces the file at path with data so that readers, and the
+// file after a crash, see either the old or the new content, never a
+// truncated one. The data is written to a temporary file in the same
+// directory, synced to disk and renamed over path. An existing file keeps
+// its permissions and, where the process may set them, its owner and group;
+// a new one is created with mode 0644. If path is a symlink, the file it
+// points to is replaced and the link is kept.
+//
+// While writing, WriteFile holds an advisory lock on path+".lock", so
+// processes that save the same file with WriteFile do not interleave. The
+// lock file is removed again before the lock is released.
+//
+// Example usage:
+//
+//	err := config.WriteFile("metadata.json", data, config.WithBackup())
+func WriteFile(path string, data []byte, opts ...Option) error {
+	return NewLoader(opts...).writeFile(path, data)
+}
+
+// writeFile implements WriteFile with the options of l.
+func (l *Loader) writeFile(path string, data []byte) error {
+	path, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	unlock, err := lock(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("config: lock %s: %w", path, err)
+	}
+	defer unlock()
+
+	perm := defaultPerm
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("config: %s is not a regular file", path)
+		}
+		perm = info.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	if l.backup && info != nil {
+		if err := copyFile(path, path+".bak", perm); err != nil {
+			return fmt.Errorf("config: backup %s: %w", path, err)
+		}
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if info != nil {
+		chownLike(tmp, info)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// lock takes an exclusive lock on the file name, creating it, and returns a
+// function that removes the file and releases the lock. The file is removed
+// while the lock is still held, so a writer that opened it before then
+// finds, once it gets the lock, that the file is no longer at name and
+// tries again with a new one.
+func lock(name string) (func(), error) {
+	for {
+		f, err := openLockFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		held, err := f.Stat()
+		if err == nil {
+			var current fs.FileInfo
+			current, err = os.Stat(name)
+			if err == nil && os.SameFile(held, current) {
+				return func() {
+					os.Remove(name)
+					unlockFile(f)
+					f.Close()
+				}, nil
+			}
+		}
+		unlockFile(f)
+		f.Close()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// resolvePath returns path with symlinks resolved, so that a symlinked file
+// is replaced at its target instead of the link being replaced by a regular
+// file. A path that does not exist is returned as it is; a symlink whose
+// target does not exist is an error.
+func resolvePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if _, lerr := os.Lstat(path); errors.Is(lerr, fs.ErrNotExist) {
+		return path, nil
+	}
+	return "", err
+}
+
+// copyFile copies src to dst, syncing dst to disk.
+func copyFile(src, dst string, perm fs.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real", "app.json")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "app.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	if err := WriteFile(link, []byte("new"), WithBackup()); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("the link was replaced: %v, %v", info, err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "new" {
+		t.Errorf("target %q, %v; want the new content", data, err)
+	}
+	if info, err := os.Stat(target); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("target mode %v, want 0600", info.Mode().Perm())
+	}
+	if data, err := os.ReadFile(target + ".bak"); err != nil || string(data) != "old" {
+		t.Errorf("backup next to the target %q, %v; want the old content", data, err)
+	}
+}
+
+func TestWriteFileDanglingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "app.json")
+	if err := os.Symlink(filepath.Join(dir, "missing", "app.json"), link); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	if err := WriteFile(link, []byte("new")); err == nil {
+		t.Error("writing through a dangling symlink succeeded")
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("the link was replaced: %v, %v", info, err)
+	}
+}
+
+func TestWriteFileLeavesNoFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"plain", nil, []string{"app.json"}},
+		{"backup", []Option{WithBackup()}, []string{"app.json", "app.json.bak"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.json")
+			for _, data := range []string{"one", "two"} {
+				if err := WriteFile(path, []byte(data), tt.opts...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("directory holds %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFileConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := strings.Repeat(string(rune('a'+i)), 1000)
+			for j := 0; j < 10; j++ {
+				if err := WriteFile(path, []byte(data)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1000 || strings.Count(string(data), string(data[:1])) != 1000 {
+		t.Errorf("the file mixes writers: %.20q...", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files left in the directory, want 1", len(entries))
+	}
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec converts a config value to and from a file format.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// ErrUnknownFormat is returned when no codec is registered for a format or
+// file extension. Use WithFormat to choose one explicitly.
+var ErrUnknownFormat = errors.New("config: unknown format")
+
+var (
+	// codecMu guards codecs and extensions.
+	codecMu sync.RWMutex
+	// codecs maps format names to codecs.
+	codecs = map[string]Codec{}
+	// extensions maps lower-case file extensions, with the dot, to format names.
+	extensions = map[string]string{}
+)
+
+func init() {
+	RegisterCodec("json", JSONCodec{}, ".json")
+	RegisterCodec("yaml", YAMLCodec{}, ".yaml", ".yml")
+	RegisterCodec("toml", TOMLCodec{}, ".toml")
+}
+
+// RegisterCodec makes a codec available under a format name and for the
+// given file extensions, e.g. RegisterCodec("ini", iniCodec{}, ".ini").
+// Registering an existing name or extension replaces it.
+func RegisterCodec(name string, codec Codec, exts ...string) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	name = strings.ToLower(name)
+	codecs[name] = codec
+	for _, ext := range exts {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions[strings.ToLower(ext)] = name
+	}
+}
+
+// CodecFor returns the codec registered under a format name such as "yaml".
+func CodecFor(name string) (Codec, error) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	codec, ok := codecs[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, name)
+	}
+	return codec, nil
+}
+
+// FormatOf returns the format name registered for the extension of path.
+func FormatOf(path string) (string, error) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	ext := strings.ToLower(filepath.Ext(path))
+	name, ok := extensions[ext]
+	if !ok {
+		return "", fmt.Errorf("%w for %s", ErrUnknownFormat, path)
+	}
+	return name, nil
+}
+
+// Formats returns the names of the registered formats.
+func Formats() []string {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithFormat selects the codec by name instead of by file extension.
+// It applies to Load, Save and the files of a Loader.
+func WithFormat(name string) Option {
+	return func(l *Loader) {
+		l.format = name
+	}
+}
+
+// codecFor returns the codec for path, honouring WithFormat.
+func (l *Loader) codecFor(path string) (Codec, error) {
+	name := l.format
+	if name == "" {
+		var err error
+		if name, err = FormatOf(path); err != nil {
+			return nil, err
+		}
+	}
+	return CodecFor(name)
+}
+
+// Load decodes the file at path into target, which may be any pointer, and
+// checks the result with Validate unless WithoutValidation is given. The
+// format is chosen from the file extension unless WithFormat is given. If
+// target is a struct pointer, its secret references are resolved as by
+// Loader.Load. Unlike Loader.Load it does not apply defaults, environment
+// or flags.
+func Load(path string, target any, opts ...Option) error {
+	l := NewLoader(opts...)
+	if err := l.decodeFile(path, target); err != nil {
+		return err
+	}
+	if isStructPointer(target) {
+		if err := l.resolveSecrets(target); err != nil {
+			return err
+		}
+	}
+	return l.validateFile(path, target)
+}
+
+// Save encodes v to the file at path. The format is chosen from the file
+// extension unless WithFormat is given. The file is replaced atomically as
+// described for WriteFile.
+func Save(path string, v any, opts ...Option) error {
+	l := NewLoader(opts...)
+	codec, err := l.codecFor(path)
+	if err != nil {
+		return err
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return l.writeFile(path, data)
+}
+
+// decodeFile decodes the file at path into target with the matching codec,
+// after migrating it if WithMigrations was given.
+func (l *Loader) decodeFile(path string, target any) error {
+	codec, err := l.codecFor(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.ToSlash(path))
+	if err != nil {
+		return err
+	}
+	if l.migrations != nil {
+		if data, err = l.migrate(path, codec, data); err != nil {
+			return err
+		}
+	}
+	if err := codec.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// JSONCodec reads and writes JSON, indented with four spaces.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// YAMLCodec reads and writes YAML using gopkg.in/yaml.v3.
+type YAMLCodec struct{}
+
+// Marshal implements Codec.
+func (YAMLCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements Codec. An empty document leaves v unchanged.
+func (YAMLCodec) Unmarshal(data []byte, v any) error {
+	return yaml.Unmarshal(data, v)
+}
+
+// TOMLCodec reads and writes TOML using github.com/BurntSushi/toml.
+type TOMLCodec struct{}
+
+// Marshal implements Codec.
+func (TOMLCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements Codec.
+func (TOMLCodec) Unmarshal(data []byte, v any) error {
+	return toml.Unmarshal(data, v)
+}
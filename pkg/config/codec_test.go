@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	type Server struct {
+		Host string `json:"host" yaml:"host" toml:"host"`
+		Port int    `json:"port" yaml:"port" toml:"port"`
+	}
+	type C struct {
+		Name    string            `json:"name" yaml:"name" toml:"name"`
+		Debug   bool              `json:"debug" yaml:"debug" toml:"debug"`
+		Ratio   float64           `json:"ratio" yaml:"ratio" toml:"ratio"`
+		Tags    []string          `json:"tags" yaml:"tags" toml:"tags"`
+		Env     map[string]string `json:"env" yaml:"env" toml:"env"`
+		Servers []Server          `json:"servers" yaml:"servers" toml:"servers"`
+	}
+	want := C{
+		Name:    "app",
+		Debug:   true,
+		Ratio:   0.5,
+		Tags:    []string{"a", "b"},
+		Env:     map[string]string{"HOME": "/home/app"},
+		Servers: []Server{{"localhost", 8080}, {"example.com", 443}},
+	}
+	for _, name := range []string{"app.json", "app.yaml", "app.yml", "app.toml", "APP.JSON"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := Save(path, want); err != nil {
+				t.Fatal(err)
+			}
+			var got C
+			if err := Load(path, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCodecUnknownFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+	v := map[string]string{"name": "app"}
+	if err := Save(path, v); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Save: got %v, want ErrUnknownFormat", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"name": "app"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	if err := Load(path, &got); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Load: got %v, want ErrUnknownFormat", err)
+	}
+	if err := Load(path, &got, WithFormat("json")); err != nil || got["name"] != "app" {
+		t.Errorf("Load with WithFormat: %v, %v", got, err)
+	}
+	if _, err := CodecFor("ini"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("CodecFor: got %v, want ErrUnknownFormat", err)
+	}
+}
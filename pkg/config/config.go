@@ -1,38 +1,64 @@
-// Package config provides configuration management utilities including environment variable
-// manipulation, key replacement, and TOML configuration file handling.
+// Package config loads, checks and saves configuration files, and prepares the
+// environment of the programs they configure.
 //
-// The package offers two main areas of functionality:
-//   - Environment variable management with key-value replacement
-//   - TOML configuration file loading and saving
+// Load and Save read and write JSON, YAML, TOML or any registered Codec, chosen
+// by file extension. A Loader layers defaults, files, environment variables and
+// flags. Loading resolves secret references (file:, env: and encrypted enc:, see
+// ResolveSecret) and checks the result with Validate. Migrations upgrade older
+// files, Watch reloads a file when it changes, and Schema and WriteExample
+// describe a config type from its struct tags. WriteFile replaces files
+// atomically, keeping their permissions, under an advisory lock.
 //
-// Key replacement allows templating in configuration strings using {KEY} syntax.
-// Environment override provides a convenient way to set multiple environment variables.
+// Expand allows templating in configuration strings using {key}, ${ENV} and ${ENV:-default} syntax.
+// Env builds environments for child processes without changing the process environment.
 //
 // Example usage:
 //
 //	// Key replacement
-//	replacements := map[string]string{"{USER}": "john", "{HOME}": "/home/john"}
-//	result := config.EnvKeyReplace("User: {USER}, Home: {HOME}", replacements)
+//	vars := map[string]string{"user": "john", "home": "/home/{user}"}
+//	result, err := config.Expand("User: {user}, Home: {home}, Shell: ${SHELL:-/bin/sh}", vars)
 //
-//	// Environment override
-//	env := map[string]string{"DEBUG": "true", "PORT": "8080"}
-//	config.EnvOverride(env)
+//	// Scoped environment for a child process
+//	env := config.Environ().With(map[string]string{"DEBUG": "true", "PORT": "8080"})
+//	cmd.Env = env.Environ()
 package config
 
 import (
 	"os"
+	"sort"
 	"strings"
 )
 
 // EnvKeyReplace replaces all {key} in the input string with their values from the replacements map.
+// Longer keys are replaced first, and replaced text is not scanned again, so the result is
+// deterministic even when keys overlap.
+//
+// Deprecated: Use Expand, which also resolves environment variables, nested
+// references and defaults, and reports reference cycles.
 func EnvKeyReplace(input string, replacements map[string]string) string {
-	for key, value := range replacements {
-		input = strings.ReplaceAll(input, key, value)
+	keys := make([]string, 0, len(replacements))
+	for key := range replacements {
+		keys = append(keys, key)
 	}
-	return input
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	pairs := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		if key != "" {
+			pairs = append(pairs, key, replacements[key])
+		}
+	}
+	return strings.NewReplacer(pairs...).Replace(input)
 }
 
 // EnvOverride sets environment variables from the provided map.
+//
+// Deprecated: EnvOverride changes the environment of the whole process. Use
+// Environ().With(env).Environ() to build the environment of a child process instead.
 func EnvOverride(env map[string]string) {
 	for k, v := range env {
 		os.Setenv(k, v)
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Env is an immutable set of environment variables. Every method that
+// changes it returns a new Env, and nothing touches the process environment,
+// so an Env can be prepared per child process and passed to exec.Cmd.Env:
+//
+//	env := config.Environ().
+//		Deny("AWS_*", "*_TOKEN").
+//		With(map[string]string{"HOME": isolatedHome})
+//	cmd.Env = env.Environ()
+//
+// On Windows variable names are case-insensitive, as in the process
+// environment; the spelling of the most recent assignment is kept.
+// The zero value is an empty environment.
+type Env struct {
+	vars map[string]envVar
+}
+
+// envVar is one variable, stored under its normalized name.
+type envVar struct {
+	name  string
+	value string
+}
+
+// Environ returns the environment of the current process.
+func Environ() Env {
+	return NewEnv(os.Environ())
+}
+
+// NewEnv returns an Env from "NAME=value" entries, as returned by
+// os.Environ. Later entries override earlier ones; entries without "=" are ignored.
+func NewEnv(entries []string) Env {
+	e := Env{vars: make(map[string]envVar, len(entries))}
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		// Windows has entries such as "=C:=C:\dir" whose name starts with "=".
+		i := strings.IndexByte(entry[1:], '=') + 1
+		if i == 0 {
+			continue
+		}
+		e.vars[envKey(entry[:i])] = envVar{name: entry[:i], value: entry[i+1:]}
+	}
+	return e
+}
+
+// EnvFromMap returns an Env holding the variables of m.
+func EnvFromMap(m map[string]string) Env {
+	return Env{}.With(m)
+}
+
+// With returns a copy of e with the variables of m added or replaced.
+func (e Env) With(m map[string]string) Env {
+	out := e.clone(len(m))
+	for name, value := range m {
+		out.vars[envKey(name)] = envVar{name: name, value: value}
+	}
+	return out
+}
+
+// Set returns a copy of e with one variable added or replaced.
+func (e Env) Set(name, value string) Env {
+	return e.With(map[string]string{name: value})
+}
+
+// Merge returns a copy of e overlaid with the variables of other.
+func (e Env) Merge(other Env) Env {
+	out := e.clone(len(other.vars))
+	for key, v := range other.vars {
+		out.vars[key] = v
+	}
+	return out
+}
+
+// Without returns a copy of e without the named variables.
+func (e Env) Without(names ...string) Env {
+	out := e.clone(0)
+	for _, name := range names {
+		delete(out.vars, envKey(name))
+	}
+	return out
+}
+
+// Allow returns a copy of e that only keeps the variables whose names match
+// one of the patterns. Patterns use path.Match syntax, e.g. "PATH" or "LC_*".
+func (e Env) Allow(patterns ...string) Env {
+	return e.filter(patterns, true)
+}
+
+// Deny returns a copy of e without the variables whose names match one of
+// the patterns. Patterns use path.Match syntax, e.g. "*_TOKEN".
+func (e Env) Deny(patterns ...string) Env {
+	return e.filter(patterns, false)
+}
+
+// Lookup returns the value of a variable and whether it is set. Its
+// signature matches os.LookupEnv, so it can be used as Expander.LookupEnv.
+func (e Env) Lookup(name string) (string, bool) {
+	v, ok := e.vars[envKey(name)]
+	return v.value, ok
+}
+
+// Get returns the value of a variable, or "" if it is not set.
+func (e Env) Get(name string) string {
+	value, _ := e.Lookup(name)
+	return value
+}
+
+// Len returns the number of variables.
+func (e Env) Len() int {
+	return len(e.vars)
+}
+
+// Names returns the variable names in sorted order.
+func (e Env) Names() []string {
+	names := make([]string, 0, len(e.vars))
+	for _, v := range e.vars {
+		names = append(names, v.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Map returns the variables as a new map.
+func (e Env) Map() map[string]string {
+	m := make(map[string]string, len(e.vars))
+	for _, v := range e.vars {
+		m[v.name] = v.value
+	}
+	return m
+}
+
+// Environ returns the variables as sorted "NAME=value" entries for exec.Cmd.Env.
+func (e Env) Environ() []string {
+	entries := make([]string, 0, len(e.vars))
+	for _, v := range e.vars {
+		entries = append(entries, v.name+"="+v.value)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// clone returns a copy of e with room for extra more variables.
+func (e Env) clone(extra int) Env {
+	out := Env{vars: make(map[string]envVar, len(e.vars)+extra)}
+	for key, v := range e.vars {
+		out.vars[key] = v
+	}
+	return out
+}
+
+// filter keeps the variables that match a pattern if keep is true, or
+// those that do not match any pattern otherwise.
+func (e Env) filter(patterns []string, keep bool) Env {
+	out := Env{vars: make(map[string]envVar)}
+	for key, v := range e.vars {
+		matched := false
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(envKey(pattern), key); ok {
+				matched = true
+				break
+			}
+		}
+		if matched == keep {
+			out.vars[key] = v
+		}
+	}
+	return out
+}
+
+// envKey normalizes a variable name for comparison. Names are
+// case-insensitive on Windows only.
+func envKey(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
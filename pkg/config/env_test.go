@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestEnvImmutable(t *testing.T) {
+	base := EnvFromMap(map[string]string{"HOME": "/home/app", "API_TOKEN": "secret", "LC_ALL": "C"})
+	want := base.Environ()
+	tests := []struct {
+		name string
+		op   func(Env) Env
+		want []string
+	}{
+		{"With", func(e Env) Env { return e.With(map[string]string{"HOME": "/tmp", "NEW": "1"}) },
+			[]string{"API_TOKEN=secret", "HOME=/tmp", "LC_ALL=C", "NEW=1"}},
+		{"Set", func(e Env) Env { return e.Set("LC_ALL", "en_US") },
+			[]string{"API_TOKEN=secret", "HOME=/home/app", "LC_ALL=en_US"}},
+		{"Without", func(e Env) Env { return e.Without("HOME", "MISSING") },
+			[]string{"API_TOKEN=secret", "LC_ALL=C"}},
+		{"Deny", func(e Env) Env { return e.Deny("*_TOKEN") },
+			[]string{"HOME=/home/app", "LC_ALL=C"}},
+		{"Allow", func(e Env) Env { return e.Allow("LC_*", "HOME") },
+			[]string{"HOME=/home/app", "LC_ALL=C"}},
+		{"Merge", func(e Env) Env { return e.Merge(EnvFromMap(map[string]string{"HOME": "/root"})) },
+			[]string{"API_TOKEN=secret", "HOME=/root", "LC_ALL=C"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.op(base).Environ(); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if got := base.Environ(); !slices.Equal(got, want) {
+				t.Errorf("%s changed the original to %v", tt.name, got)
+			}
+		})
+	}
+
+	var zero Env
+	if zero.Set("A", "1").Len() != 1 || zero.Len() != 0 {
+		t.Error("Set on the zero Env changed it")
+	}
+}
+
+func TestEnvLeavesProcessAlone(t *testing.T) {
+	t.Setenv("TEST_CONFIG_ENV", "process")
+	env := Environ().Set("TEST_CONFIG_ENV", "child").Without("PATH")
+	if got := os.Getenv("TEST_CONFIG_ENV"); got != "process" {
+		t.Errorf("process variable = %q, want it unchanged", got)
+	}
+	if _, ok := os.LookupEnv("PATH"); !ok {
+		t.Error("Without removed PATH from the process")
+	}
+	if got := env.Get("TEST_CONFIG_ENV"); got != "child" {
+		t.Errorf("env variable = %q, want child", got)
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Example returns an example config file in the named format, such as
+// "yaml", holding the values of v. Fields that are zero in v show the value
+// of their default tag. In YAML and TOML every key is preceded by a comment
+// built from its doc, default, validate and secret tags; JSON has no
+// comments, so use Schema to document it.
+//
+// Example usage:
+//
+//	data, err := config.Example(&Config{Servers: []string{"http://localhost:8080"}}, "yaml")
+func Example(v any, format string) ([]byte, error) {
+	codec, err := CodecFor(format)
+	if err != nil {
+		return nil, err
+	}
+	v, err = withDefaults(v)
+	if err != nil {
+		return nil, err
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	switch strings.ToLower(format) {
+	case "yaml":
+		return commentYAML(data, reflect.TypeOf(v))
+	case "toml":
+		return commentTOML(data, reflect.TypeOf(v)), nil
+	}
+	return data, nil
+}
+
+// WriteExample writes Example(v) to a new file at path, in the format of its
+// extension unless WithFormat is given. It does not overwrite an existing file.
+func WriteExample(path string, v any, opts ...Option) error {
+	l := NewLoader(opts...)
+	format := l.format
+	if format == "" {
+		var err error
+		if format, err = FormatOf(path); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("config: %s already exists", path)
+	}
+	data, err := Example(v, format)
+	if err != nil {
+		return err
+	}
+	return l.writeFile(path, data)
+}
+
+// withDefaults returns a copy of the struct v, or of the struct it points
+// to, with the default tags applied to its zero fields. Other values are
+// returned unchanged.
+func withDefaults(v any) (any, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return v, nil
+	}
+	cp := reflect.New(rv.Type())
+	cp.Elem().Set(rv)
+	err := walkFields(cp.Interface(), func(f field) error {
+		def, ok := f.sf.Tag.Lookup("default")
+		if !ok || !f.value.IsZero() {
+			return nil
+		}
+		if err := setValue(f.value, def); err != nil {
+			return fmt.Errorf("config: default of %s: %w", f.path, err)
+		}
+		return nil
+	})
+	return cp.Interface(), err
+}
+
+// fieldComment returns the comment lines that document a field.
+func fieldComment(sf reflect.StructField) []string {
+	var lines []string
+	if doc := sf.Tag.Get("doc"); doc != "" {
+		lines = append(lines, doc)
+	}
+	if rules := sf.Tag.Get("validate"); rules != "" {
+		lines = append(lines, "Rules: "+rules)
+	}
+	if def, ok := sf.Tag.Lookup("default"); ok {
+		lines = append(lines, "Default: "+def)
+	}
+	if sf.Tag.Get("secret") == "true" {
+		lines = append(lines, "May be a file:, env: or enc: secret reference.")
+	}
+	return lines
+}
+
+// formatFields maps the keys of the struct type t in a format to its
+// fields. Keys come from the format's struct tag or the field name, which
+// YAML lowercases. Embedded structs are flattened.
+func formatFields(t reflect.Type, format string) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(sf.Tag.Get(format), ",")
+		if key == "-" {
+			continue
+		}
+		if sf.Anonymous && key == "" && isNestedType(sf.Type) {
+			for k, f := range formatFields(derefType(sf.Type), format) {
+				fields[k] = f
+			}
+			continue
+		}
+		if key == "" {
+			key = sf.Name
+			if format == "yaml" {
+				key = strings.ToLower(sf.Name)
+			}
+		}
+		fields[key] = sf
+	}
+	return fields
+}
+
+// derefType returns the type t points to, if it is a pointer.
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// commentYAML adds field comments to the YAML document data of type t.
+func commentYAML(data []byte, t reflect.Type) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+	commentNode(doc.Content[0], t)
+	return YAMLCodec{}.Marshal(&doc)
+}
+
+// commentNode adds field comments to the keys of n, a value of type t.
+// Only the first element of a sequence is commented.
+func commentNode(n *yaml.Node, t reflect.Type) {
+	t = derefType(t)
+	switch n.Kind {
+	case yaml.MappingNode:
+		if t.Kind() == reflect.Map {
+			for i := 1; i < len(n.Content); i += 2 {
+				commentNode(n.Content[i], t.Elem())
+			}
+			return
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		fields := formatFields(t, "yaml")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			sf, ok := fields[n.Content[i].Value]
+			if !ok {
+				continue
+			}
+			if lines := fieldComment(sf); len(lines) > 0 {
+				n.Content[i].HeadComment = "# " + strings.Join(lines, "\n# ")
+			}
+			commentNode(n.Content[i+1], sf.Type)
+		}
+	case yaml.SequenceNode:
+		if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && len(n.Content) > 0 {
+			commentNode(n.Content[0], t.Elem())
+		}
+	}
+}
+
+// commentTOML adds field comments to the TOML document data of type t,
+// before each key and table header. Only the first of an array of tables
+// is commented.
+func commentTOML(data []byte, t reflect.Type) []byte {
+	root := derefType(t)
+	table := root
+	seen := make(map[string]bool)
+	repeated := false
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		var sf reflect.StructField
+		var found bool
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "["):
+			repeated = seen[trimmed]
+			seen[trimmed] = true
+			sf, table, found = tomlTable(root, strings.Trim(trimmed, "[]"))
+		default:
+			key, _, _ := strings.Cut(trimmed, " = ")
+			if table.Kind() == reflect.Struct {
+				sf, found = formatFields(table, "toml")[strings.Trim(key, `"'`)]
+			}
+		}
+		if found && !repeated {
+			for _, c := range fieldComment(sf) {
+				b.WriteString(indent + "# " + c + "\n")
+			}
+		}
+		b.WriteString(line)
+	}
+	return []byte(b.String())
+}
+
+// tomlTable looks up the dotted table path in the struct type root. It
+// returns the field of the last key, the type whose keys the table holds,
+// and whether the last key is a struct field.
+func tomlTable(root reflect.Type, path string) (reflect.StructField, reflect.Type, bool) {
+	t := root
+	var sf reflect.StructField
+	found := false
+	for _, key := range strings.Split(path, ".") {
+		found = false
+		if t.Kind() == reflect.Struct {
+			sf, found = formatFields(t, "toml")[strings.Trim(key, `"'`)]
+			if !found {
+				return sf, t, false
+			}
+			t = derefType(sf.Type)
+		} else if t.Kind() == reflect.Map {
+			t = derefType(t.Elem())
+		}
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = derefType(t.Elem())
+		}
+	}
+	return sf, t, found
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExample(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `{
+    "name": "app",
+    "mode": "",
+    "ratio": 0.5,
+    "timeout": 60000000000,
+    "tags": [
+        "a"
+    ],
+    "pass": "",
+    "server": {
+        "host": "",
+        "port": 8080
+    }
+}`},
+		{"yaml", `# Name of the app
+# Rules: min=2,max=10
+# Default: app
+name: app
+# Rules: oneof=dev prod
+mode: ""
+# Rules: max=1
+# Default: 0.5
+ratio: 0.5
+# Default: 1m
+timeout: 1m0s
+# Rules: max=3
+tags:
+  - a
+# May be a file:, env: or enc: secret reference.
+pass: ""
+server:
+  # Host name
+  # Rules: required
+  host: ""
+  # Rules: min=1,max=65535
+  # Default: 8080
+  port: 8080
+`},
+		{"toml", `# Name of the app
+# Rules: min=2,max=10
+# Default: app
+name = "app"
+# Rules: oneof=dev prod
+mode = ""
+# Rules: max=1
+# Default: 0.5
+ratio = 0.5
+# Default: 1m
+timeout = "1m0s"
+# Rules: max=3
+tags = ["a"]
+# May be a file:, env: or enc: secret reference.
+pass = ""
+
+[server]
+  # Host name
+  # Rules: required
+  host = ""
+  # Rules: min=1,max=65535
+  # Default: 8080
+  port = 8080
+`},
+	}
+	v := &schemaConfig{Tags: []string{"a"}}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := Example(v, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(got)) != strings.TrimSpace(tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+
+			// The example decodes back to the values it shows.
+			var back schemaConfig
+			codec, _ := CodecFor(tt.format)
+			if err := codec.Unmarshal(got, &back); err != nil {
+				t.Fatal(err)
+			}
+			want := schemaConfig{Name: "app", Ratio: 0.5, Timeout: time.Minute, Tags: []string{"a"}, Server: schemaServer{Port: 8080}}
+			if !reflect.DeepEqual(back, want) {
+				t.Errorf("decoded %+v, want %+v", back, want)
+			}
+		})
+	}
+	if v.Name != "" || v.Server.Port != 0 {
+		t.Errorf("Example changed its argument to %+v", v)
+	}
+}
+
+func TestExampleKeepsValues(t *testing.T) {
+	got, err := Example(schemaConfig{Name: "mine", Server: schemaServer{Port: 1}}, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"name: mine", "port: 1", "ratio: 0.5"} {
+		if !strings.Contains(string(got), line+"\n") {
+			t.Errorf("example lacks %q:\n%s", line, got)
+		}
+	}
+}
+
+func TestWriteExample(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	if err := WriteExample(path, &schemaConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := Example(&schemaConfig{}, "yaml")
+	if got, err := os.ReadFile(path); err != nil || string(got) != string(want) {
+		t.Errorf("file holds %q, %v; want %q", got, err, want)
+	}
+	if err := WriteExample(path, &schemaConfig{}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("overwriting: got %v, want an error", err)
+	}
+	conf := filepath.Join(dir, "app.conf")
+	if err := WriteExample(conf, &schemaConfig{}, WithFormat("toml")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(conf); !strings.Contains(string(data), "[server]") {
+		t.Errorf("WithFormat(toml) wrote %q", data)
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrUnknownKey is returned by Expand for a ${NAME} without default
+	// whose environment variable is not set.
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrCycle is returned by Expand when keys reference each other in a loop.
+	ErrCycle = errors.New("reference cycle")
+)
+
+// Expander substitutes references in strings:
+//
+//	{key}             the value of key in Vars, which is expanded itself
+//	${NAME}           the environment variable NAME
+//	${NAME:-default}  NAME if it is set and not empty, otherwise the expanded default
+//	{{ and }}         a literal { and }
+//	$$                a literal $
+//
+// Only keys present in Vars are references, so other braces, such as those
+// of a GUID like "{1b4e28ba-2fa1-11d2-883f-0016d3cca427}", are kept as is.
+// NAME must be an identifier of letters, digits and '_' that does not start
+// with a digit; a dollar sign that does not start a reference, such as in
+// "$HOME", is kept as well. Unset variables without default and reference
+// cycles are errors rather than left in place.
+type Expander struct {
+	// Vars holds the values of {key} references.
+	Vars map[string]string
+	// LookupEnv resolves ${NAME} references. It defaults to os.LookupEnv;
+	// use an Env's Lookup method to expand against a scoped environment.
+	LookupEnv func(name string) (string, bool)
+}
+
+// NewExpander returns an Expander for vars that reads the process environment.
+func NewExpander(vars map[string]string) *Expander {
+	return &Expander{Vars: vars, LookupEnv: os.LookupEnv}
+}
+
+// Expand is a shorthand for NewExpander(vars).Expand(s).
+func Expand(s string, vars map[string]string) (string, error) {
+	return NewExpander(vars).Expand(s)
+}
+
+// Expand returns s with all references substituted.
+func (e *Expander) Expand(s string) (string, error) {
+	return e.expand(s, nil)
+}
+
+// expand substitutes references in s. stack holds the keys being expanded,
+// outermost first, to detect cycles.
+func (e *Expander) expand(s string, stack []string) (string, error) {
+	if !strings.ContainsAny(s, "{}$") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c == '{' || c == '}' || c == '$') && i+1 < len(s) && s[i+1] == c:
+			b.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(s[i+1:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			key := s[i+1 : i+1+end]
+			if _, ok := e.Vars[key]; !ok {
+				b.WriteByte(c)
+				continue
+			}
+			value, err := e.lookupVar(key, stack)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end + 1
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			ref := s[i+2 : i+2+end]
+			name, def, hasDef := strings.Cut(ref, ":-")
+			if !isEnvName(name) {
+				b.WriteByte(c)
+				continue
+			}
+			if hasDef {
+				// The default may contain a nested reference with its own braces.
+				if n := matchingBrace(s[i+2:]); n > end {
+					end = n
+					def = s[i+2+len(name)+2 : i+2+end]
+				}
+			}
+			value, err := e.lookupEnv(name, def, hasDef, stack)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end + 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// lookupVar returns the expanded value of a {key} reference.
+func (e *Expander) lookupVar(key string, stack []string) (string, error) {
+	for i, k := range stack {
+		if k == key {
+			return "", fmt.Errorf("config: %w: %s", ErrCycle, strings.Join(append(stack[i:], key), " -> "))
+		}
+	}
+	return e.expand(e.Vars[key], append(stack, key))
+}
+
+// lookupEnv returns the value of a ${NAME} or ${NAME:-default} reference.
+func (e *Expander) lookupEnv(name, def string, hasDef bool, stack []string) (string, error) {
+	var value string
+	var ok bool
+	if e.LookupEnv != nil {
+		value, ok = e.LookupEnv(name)
+	}
+	if hasDef && value == "" {
+		return e.expand(def, stack)
+	}
+	if !ok {
+		return "", fmt.Errorf("config: %w ${%s}", ErrUnknownKey, name)
+	}
+	return value, nil
+}
+
+// matchingBrace returns the index of the '}' closing a reference that starts
+// after "${" in s, allowing nested braces, or -1.
+func matchingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// isEnvName reports whether s is a valid environment variable name.
+func isEnvName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{
+		"drive": "E:",
+		"work":  "{drive}/apps",
+		"a":     "{b}",
+		"b":     "{a}",
+		"self":  "x{self}",
+	}
+	env := map[string]string{"APP": "tool", "EMPTY": ""}
+	e := &Expander{Vars: vars, LookupEnv: func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}}
+	tests := []struct {
+		in, want string
+		err      error
+	}{
+		{"plain", "plain", nil},
+		{"{drive}", "E:", nil},
+		{"{work}/run.exe", "E:/apps/run.exe", nil},
+		{"${APP}.exe", "tool.exe", nil},
+		{"${MISSING:-default}", "default", nil},
+		{"${EMPTY:-default}", "default", nil},
+		{"${APP:-default}", "tool", nil},
+		{"${MISSING:-{work}/${APP}}", "E:/apps/tool", nil},
+		{"${MISSING:-}", "", nil},
+		{"$$HOME and $${APP}", "$HOME and ${APP}", nil},
+		{"{{drive}} and }}", "{drive} and }", nil},
+		{"$HOME and $", "$HOME and $", nil},
+		{"{1b4e28ba-2fa1-11d2-883f-0016d3cca427}", "{1b4e28ba-2fa1-11d2-883f-0016d3cca427}", nil},
+		{"{unknown} and { x } and {", "{unknown} and { x } and {", nil},
+		{"${1ABC} and ${A-B} and ${", "${1ABC} and ${A-B} and ${", nil},
+		{"${MISSING}", "", ErrUnknownKey},
+		{"{a}", "", ErrCycle},
+		{"{self}", "", ErrCycle},
+	}
+	for _, tt := range tests {
+		got, err := e.Expand(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Expand(%q): error %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
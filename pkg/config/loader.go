@@ -0,0 +1,442 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Loader fills a config struct from several layers. Later layers override
+// earlier ones:
+//
+//  1. defaults from `default:"..."` struct tags
+//  2. config files, in the order they were added, decoded by the codec
+//     registered for their extension (see RegisterCodec and WithFormat)
+//  3. environment variables, from `env:"NAME"` tags or derived from the
+//     field path when a prefix is set (PREFIX_NOTIFY_EMAIL_USER)
+//  4. command-line flags from `flag:"name"` tags that were set explicitly
+//
+// Only values that are present in a layer override the previous one, so a
+// file that omits a key keeps its default.
+//
+// Example usage:
+//
+//	type Config struct {
+//		Addr    string        `toml:"addr" default:":8080" env:"ADDR" flag:"addr" doc:"Listen address"`
+//		Timeout time.Duration `toml:"timeout" default:"10s"`
+//	}
+//
+//	loader := config.NewLoader(
+//		config.WithOptionalFiles("/etc/app.toml", "app.toml"),
+//		config.WithEnvPrefix("APP"),
+//		config.WithFlags(flag.CommandLine),
+//	)
+//	var cfg Config
+//	loader.RegisterFlags(&cfg) // before flag.Parse
+//	flag.Parse()
+//	err := loader.Load(&cfg)
+type Loader struct {
+	files     []loaderFile
+	envPrefix string
+	flags     *flag.FlagSet
+	format    string
+	backup    bool
+	keyFile   string
+	secretKey []byte // read from keyFile on first use
+
+	migrations *Migrations
+	rewrite    bool
+	noValidate bool
+}
+
+// loaderFile is a config file added to a Loader.
+type loaderFile struct {
+	path     string
+	optional bool
+}
+
+// Option configures a Loader.
+type Option func(*Loader)
+
+// WithFiles adds config files that must exist.
+func WithFiles(paths ...string) Option {
+	return func(l *Loader) {
+		for _, p := range paths {
+			l.files = append(l.files, loaderFile{path: p})
+		}
+	}
+}
+
+// WithOptionalFiles adds config files that are skipped if they do not exist.
+func WithOptionalFiles(paths ...string) Option {
+	return func(l *Loader) {
+		for _, p := range paths {
+			l.files = append(l.files, loaderFile{path: p, optional: true})
+		}
+	}
+}
+
+// WithEnvPrefix enables environment variables for every field. A field
+// without an env tag is read from PREFIX_ followed by its key path in upper
+// case, and a field with an env tag from PREFIX_ followed by the tag.
+func WithEnvPrefix(prefix string) Option {
+	return func(l *Loader) {
+		l.envPrefix = strings.TrimSuffix(strings.ToUpper(prefix), "_")
+	}
+}
+
+// WithFlags makes RegisterFlags define the flags of the config on fs and
+// Load apply the flags that were set. Without it only env tags are used.
+func WithFlags(fs *flag.FlagSet) Option {
+	return func(l *Loader) {
+		l.flags = fs
+	}
+}
+
+// NewLoader returns a Loader configured by opts.
+func NewLoader(opts ...Option) *Loader {
+	l := &Loader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// RegisterFlags defines a flag for every field of target with a flag tag.
+// The flag usage is taken from the doc tag and its default from the default
+// tag. Call it before the flag set is parsed. Flags that already exist are skipped.
+func (l *Loader) RegisterFlags(target any) error {
+	if l.flags == nil {
+		return nil
+	}
+	v, err := targetStruct(target)
+	if err != nil {
+		return err
+	}
+	// Only the tags are needed; walking a zero copy leaves nil sections of
+	// target alone.
+	return walkFields(reflect.New(v.Type()).Interface(), func(f field) error {
+		name := f.tag("flag")
+		if name == "" || l.flags.Lookup(name) != nil {
+			return nil
+		}
+		fv := &flagValue{value: f.tag("default"), isBool: f.value.Kind() == reflect.Bool}
+		l.flags.Var(fv, name, f.tag("doc"))
+		return nil
+	})
+}
+
+// Load fills target, which must be a pointer to a struct, from all layers,
+// resolves the secret references of fields tagged `secret:"true"` (see
+// ResolveSecret) and then checks the result with Validate. A
+// *ValidationError names the files that were read.
+func (l *Loader) Load(target any) error {
+	if err := SetDefaults(target); err != nil {
+		return err
+	}
+	var read []string
+	for _, file := range l.files {
+		if err := l.decodeFile(file.path, target); err != nil {
+			if file.optional && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		read = append(read, file.path)
+	}
+	if err := l.applyEnv(target); err != nil {
+		return err
+	}
+	if err := l.applyFlags(target); err != nil {
+		return err
+	}
+	if err := l.resolveSecrets(target); err != nil {
+		return err
+	}
+	return l.validateFile(strings.Join(read, ", "), target)
+}
+
+// applyEnv sets fields from environment variables.
+func (l *Loader) applyEnv(target any) error {
+	return walkFields(target, func(f field) error {
+		name := l.envName(f)
+		if name == "" {
+			return nil
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if err := setValue(f.value, value); err != nil {
+			return fmt.Errorf("config: env %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// envName returns the environment variable for a field, or "" if it has none.
+func (l *Loader) envName(f field) string {
+	name := f.tag("env")
+	if name == "-" {
+		return ""
+	}
+	if l.envPrefix == "" {
+		return name
+	}
+	if name == "" {
+		name = strings.ToUpper(strings.ReplaceAll(f.path, ".", "_"))
+	}
+	return l.envPrefix + "_" + name
+}
+
+// applyFlags sets fields from the flags that were given on the command line.
+func (l *Loader) applyFlags(target any) error {
+	if l.flags == nil || !l.flags.Parsed() {
+		return nil
+	}
+	set := make(map[string]bool)
+	l.flags.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	return walkFields(target, func(f field) error {
+		name := f.tag("flag")
+		if name == "" || !set[name] {
+			return nil
+		}
+		value := l.flags.Lookup(name).Value.String()
+		if err := setValue(f.value, value); err != nil {
+			return fmt.Errorf("config: flag -%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// SetDefaults sets every field of target that has a default tag, replacing
+// its current value. Use it to reset a config before decoding into it.
+func SetDefaults(target any) error {
+	return walkFields(target, func(f field) error {
+		def, ok := f.sf.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+		if err := setValue(f.value, def); err != nil {
+			return fmt.Errorf("config: default of %s: %w", f.path, err)
+		}
+		return nil
+	})
+}
+
+// flagValue stores the raw string of a config flag until Load applies it.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+// String implements flag.Value.
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+// Set implements flag.Value.
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be set with -name instead of -name=true.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// field is a settable struct field found by walkFields.
+type field struct {
+	// path is the dotted key path, e.g. "notify.email.user".
+	path  string
+	sf    reflect.StructField
+	value reflect.Value
+}
+
+// tag returns the value of the named struct tag.
+func (f field) tag(name string) string {
+	return f.sf.Tag.Get(name)
+}
+
+// walkFields calls fn for every exported leaf field of the struct pointed to
+// by target. Nested structs are descended into unless they implement
+// encoding.TextUnmarshaler; nil struct pointers are allocated.
+func walkFields(target any, fn func(field) error) error {
+	v, err := targetStruct(target)
+	if err != nil {
+		return err
+	}
+	return walkStruct(v, "", true, fn)
+}
+
+// walkSetFields is walkFields for passes that only change fields that are
+// already there: nil struct pointers are skipped rather than allocated, so
+// that absent optional sections stay nil.
+func walkSetFields(target any, fn func(field) error) error {
+	v, err := targetStruct(target)
+	if err != nil {
+		return err
+	}
+	return walkStruct(v, "", false, fn)
+}
+
+// targetStruct returns the struct that target points to.
+func targetStruct(target any) (reflect.Value, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("config: target must be a non-nil pointer to a struct, got %T", target)
+	}
+	return v.Elem(), nil
+}
+
+// walkStruct is the recursive part of walkFields. Nil struct pointers are
+// allocated if alloc is set and skipped otherwise.
+func walkStruct(v reflect.Value, prefix string, alloc bool, fn func(field) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := fieldKey(sf)
+		if key == "-" {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		fv := v.Field(i)
+		if isNested(fv) {
+			if sf.Anonymous {
+				path = prefix
+			}
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					if !alloc {
+						continue
+					}
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if err := walkStruct(fv, path, alloc, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(field{path: path, sf: sf, value: fv}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNested reports whether v is a struct (or struct pointer) to descend into.
+func isNested(v reflect.Value) bool {
+	return isNestedType(v.Type())
+}
+
+// isNestedType reports whether t is a struct (or struct pointer) to descend into.
+func isNestedType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// fieldKey returns the config key of a field: its toml, json or yaml tag
+// name, or the field name in lower case.
+func fieldKey(sf reflect.StructField) string {
+	for _, tag := range []string{"toml", "json", "yaml"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(sf.Name)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// setValue parses s into v. Supported are strings, booleans, numbers,
+// time.Duration, encoding.TextUnmarshaler and slices of those, which are
+// given as comma-separated lists.
+func setValue(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if s == "" {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoaderPrecedence(t *testing.T) {
+	type Nested struct {
+		User string `json:"user" default:"default-user"`
+	}
+	type C struct {
+		Addr   string `json:"addr" default:":80" env:"ADDR" flag:"addr"`
+		Port   int    `json:"port" default:"1"`
+		Nested Nested `json:"nested"`
+	}
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want C
+	}{
+		{"defaults", "", nil, nil, C{":80", 1, Nested{"default-user"}}},
+		{"file over defaults", `{"addr": ":81", "nested": {"user": "file-user"}}`, nil, nil,
+			C{":81", 1, Nested{"file-user"}}},
+		{"env over file", `{"addr": ":81", "port": 2}`, map[string]string{"TESTCFG_ADDR": ":82", "TESTCFG_NESTED_USER": "env-user"}, nil,
+			C{":82", 2, Nested{"env-user"}}},
+		{"flags over env", `{"addr": ":81"}`, map[string]string{"TESTCFG_ADDR": ":82"}, []string{"-addr", ":83"},
+			C{":83", 1, Nested{"default-user"}}},
+		{"unset flags keep env", "", map[string]string{"TESTCFG_ADDR": ":82", "TESTCFG_PORT": "3"}, []string{},
+			C{":82", 3, Nested{"default-user"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.json")
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			l := NewLoader(WithOptionalFiles(path), WithEnvPrefix("TESTCFG"), WithFlags(fs))
+			var c C
+			if err := l.RegisterFlags(&c); err != nil {
+				t.Fatal(err)
+			}
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Load(&c); err != nil {
+				t.Fatal(err)
+			}
+			if c != tt.want {
+				t.Errorf("got %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoaderFiles(t *testing.T) {
+	type C struct {
+		A string `json:"a"`
+		B string `json:"b"`
+	}
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.json"), filepath.Join(dir, "second.json")
+	os.WriteFile(first, []byte(`{"a": "first", "b": "first"}`), 0644)
+	os.WriteFile(second, []byte(`{"b": "second"}`), 0644)
+	missing := filepath.Join(dir, "missing.json")
+
+	var c C
+	if err := NewLoader(WithFiles(first, second), WithOptionalFiles(missing)).Load(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c != (C{"first", "second"}) {
+		t.Errorf("got %+v, want later files to override earlier ones", c)
+	}
+	if err := NewLoader(WithFiles(missing)).Load(&c); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing required file: got %v, want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !unix && !windows
+
+package config
+
+import "os"
+
+// openLockFile opens the lock file at path, creating it if needed.
+func openLockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+}
+
+// lockFile does nothing on platforms without file locking.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without file locking.
+func unlockFile(f *os.File) {}
+
+// chownLike does nothing on platforms without file owners.
+func chownLike(f *os.File, info os.FileInfo) {}
+
+// syncDir does nothing on platforms without directory sync.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// openLockFile opens the lock file at path, creating it if needed.
+func openLockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+}
+
+// lockFile takes an exclusive advisory lock on f and blocks until the lock
+// is acquired.
+func lockFile(f *os.File) error {
+	for {
+		err := unix.Flock(int(f.Fd()), unix.LOCK_EX)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
+
+// unlockFile releases the lock taken by lockFile.
+func unlockFile(f *os.File) {
+	unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
+
+// chownLike gives f the owner and group of the file described by info. A
+// process without the privilege to change the owner still tries the group,
+// which it may set to any group it belongs to; otherwise f keeps its own.
+func chownLike(f *os.File, info os.FileInfo) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	if err := f.Chown(int(st.Uid), int(st.Gid)); err != nil {
+		f.Chown(-1, int(st.Gid))
+	}
+}
+
+// syncDir flushes the directory entry of a renamed file to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
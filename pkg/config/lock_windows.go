@@ -0,0 +1,54 @@
+//go:build windows
+
+package config
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// openLockFile opens the lock file at path, creating it if needed. The file
+// is shared for deletion so that the holder of the lock can remove it while
+// others have it open. A file that is being deleted cannot be opened until
+// the last handle to it is closed, so opening is retried for a while.
+func openLockFile(path string) (*os.File, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		h, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE,
+			windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+			nil, windows.OPEN_ALWAYS, windows.FILE_ATTRIBUTE_NORMAL, 0)
+		if err == nil {
+			return os.NewFile(uintptr(h), path), nil
+		}
+		if err != windows.ERROR_ACCESS_DENIED || attempt == 100 {
+			return nil, &os.PathError{Op: "open", Path: path, Err: err}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// lockFile takes an exclusive lock on f and blocks until the lock is
+// acquired.
+func lockFile(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, new(windows.Overlapped))
+}
+
+// unlockFile releases the lock taken by lockFile.
+func unlockFile(f *os.File) {
+	windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, new(windows.Overlapped))
+}
+
+// chownLike does nothing on Windows, where files inherit the ACLs of their
+// directory.
+func chownLike(f *os.File, info os.FileInfo) {}
+
+// syncDir does nothing on Windows, where directories cannot be synced and
+// MoveFileEx already makes the rename durable.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrNewerVersion is returned when a config document has a higher version
+// than the program knows, i.e. it was written by a newer release.
+var ErrNewerVersion = errors.New("written by a newer version")
+
+// MigrationFunc upgrades a config document by one version, in place. The
+// document is the file decoded into a map, as by the file's codec.
+type MigrationFunc func(doc map[string]any) error
+
+// Migrations upgrade config documents from older versions to the current
+// one. The version of a document is stored under Key; documents without it
+// are version 0. On load every step from the document's version up to
+// Current is applied in order, after which the document is decoded into the
+// config struct, which should have a field for Key so that saving it keeps
+// the version.
+//
+// Example usage:
+//
+//	var migrations = config.NewMigrations(2).
+//		Register(0, func(doc map[string]any) error {
+//			doc["workDir"] = doc["workdir"] // renamed in version 1
+//			delete(doc, "workdir")
+//			return nil
+//		}).
+//		Register(1, func(doc map[string]any) error {
+//			doc["isolated"] = doc["isolate"] == "yes"
+//			delete(doc, "isolate")
+//			return nil
+//		})
+//
+//	err := config.Load(path, &cfg, config.WithMigrations(migrations))
+type Migrations struct {
+	// Key is the name of the version key, "version" by default.
+	Key string
+	// Current is the version that the program reads and writes.
+	Current int
+
+	steps map[int]MigrationFunc
+}
+
+// NewMigrations returns Migrations for documents of the current version.
+func NewMigrations(current int) *Migrations {
+	return &Migrations{Key: "version", Current: current, steps: make(map[int]MigrationFunc)}
+}
+
+// Register adds the step that upgrades documents of version from to
+// version from+1. Versions without a step are upgraded unchanged. It panics
+// if a step for from is already registered or from is not below Current.
+func (m *Migrations) Register(from int, fn MigrationFunc) *Migrations {
+	if from < 0 || from >= m.Current {
+		panic(fmt.Sprintf("config: migration from version %d, current version is %d", from, m.Current))
+	}
+	if _, ok := m.steps[from]; ok {
+		panic(fmt.Sprintf("config: migration from version %d registered twice", from))
+	}
+	if m.steps == nil {
+		m.steps = make(map[int]MigrationFunc)
+	}
+	m.steps[from] = fn
+	return m
+}
+
+// Migrate upgrades doc to the current version and reports whether it
+// changed. A document of a newer version returns an error wrapping ErrNewerVersion.
+func (m *Migrations) Migrate(doc map[string]any) (bool, error) {
+	version, err := docVersion(doc[m.Key])
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", m.Key, err)
+	}
+	if version > m.Current {
+		return false, fmt.Errorf("%w: version %d, this program reads up to %d", ErrNewerVersion, version, m.Current)
+	}
+	if version == m.Current {
+		return false, nil
+	}
+	for ; version < m.Current; version++ {
+		if step, ok := m.steps[version]; ok {
+			if err := step(doc); err != nil {
+				return false, fmt.Errorf("migrating from version %d: %w", version, err)
+			}
+		}
+	}
+	doc[m.Key] = m.Current
+	return true, nil
+}
+
+// WithMigrations upgrades older config files with m when they are loaded by
+// Load, Loader.Load or Watch. The files must hold a table or object at the
+// top level.
+func WithMigrations(m *Migrations) Option {
+	return func(l *Loader) {
+		l.migrations = m
+	}
+}
+
+// WithRewrite makes loading write migrated files back in the current
+// version, keeping the previous file as path+".bak". The rewritten file is
+// encoded from the migrated document, so its keys may be reordered and
+// comments are lost.
+func WithRewrite() Option {
+	return func(l *Loader) {
+		l.rewrite = true
+	}
+}
+
+// migrate upgrades the file data read from path, and rewrites the file if
+// requested. It returns the data to decode.
+func (l *Loader) migrate(path string, codec Codec, data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := codec.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if doc == nil {
+		doc = make(map[string]any)
+	}
+	changed, err := l.migrations.Migrate(doc)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if !changed {
+		return data, nil
+	}
+	data, err = codec.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if l.rewrite {
+		rewriter := *l
+		rewriter.backup = true
+		if err := rewriter.writeFile(path, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// docVersion converts a decoded version value to an int. A missing value is version 0.
+func docVersion(v any) (int, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case uint64:
+		return int(n), nil
+	case float64:
+		if n == math.Trunc(n) {
+			return int(n), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(n); err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid version %v", v)
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	// Version 1 has no step, so documents pass through it unchanged.
+	m := NewMigrations(3).
+		Register(0, func(doc map[string]any) error {
+			doc["workDir"] = doc["workdir"]
+			delete(doc, "workdir")
+			return nil
+		}).
+		Register(2, func(doc map[string]any) error {
+			doc["isolated"] = doc["isolate"] == "yes"
+			delete(doc, "isolate")
+			return nil
+		})
+	tests := []struct {
+		name    string
+		doc     map[string]any
+		want    map[string]any
+		changed bool
+		err     string
+	}{
+		{"version 0", map[string]any{"workdir": "w", "isolate": "yes"},
+			map[string]any{"version": 3, "workDir": "w", "isolated": true}, true, ""},
+		{"version 1 skips the missing step", map[string]any{"version": 1, "workdir": "w", "isolate": "no"},
+			map[string]any{"version": 3, "workdir": "w", "isolated": false}, true, ""},
+		{"invalid string version", map[string]any{"version": "2.0", "isolate": "yes"}, nil, false, "invalid version"},
+		{"float version", map[string]any{"version": float64(2), "isolate": "yes"},
+			map[string]any{"version": 3, "isolated": true}, true, ""},
+		{"current", map[string]any{"version": 3, "x": 1}, map[string]any{"version": 3, "x": 1}, false, ""},
+		{"newer", map[string]any{"version": 4}, nil, false, "newer version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := m.Migrate(tt.doc)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != tt.changed || !reflect.DeepEqual(tt.doc, tt.want) {
+				t.Errorf("got %v (changed %v), want %v (changed %v)", tt.doc, changed, tt.want, tt.changed)
+			}
+		})
+	}
+
+	if _, err := m.Migrate(map[string]any{"version": 5}); !errors.Is(err, ErrNewerVersion) {
+		t.Errorf("got %v, want ErrNewerVersion", err)
+	}
+	failing := NewMigrations(2).Register(1, func(doc map[string]any) error { return errors.New("boom") })
+	if _, err := failing.Migrate(map[string]any{}); err == nil || !strings.Contains(err.Error(), "migrating from version 1: boom") {
+		t.Errorf("got %v, want the error of the failing step", err)
+	}
+}
+
+func TestMigrateRegisterPanics(t *testing.T) {
+	for _, from := range []int{-1, 2, 3} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%d) did not panic", from)
+				}
+			}()
+			NewMigrations(2).Register(1, func(map[string]any) error { return nil }).Register(from, nil)
+		}()
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a step twice did not panic")
+		}
+	}()
+	NewMigrations(2).Register(0, nil).Register(0, nil)
+}
+
+func TestLoadRewritesMigratedFile(t *testing.T) {
+	type C struct {
+		Version int    `json:"version"`
+		WorkDir string `json:"workDir"`
+	}
+	m := NewMigrations(1).Register(0, func(doc map[string]any) error {
+		doc["workDir"] = doc["workdir"]
+		delete(doc, "workdir")
+		return nil
+	})
+	path := filepath.Join(t.TempDir(), "app.json")
+	old := `{"workdir": "w"}`
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var c C
+	if err := Load(path, &c, WithMigrations(m), WithRewrite()); err != nil {
+		t.Fatal(err)
+	}
+	if c != (C{1, "w"}) {
+		t.Errorf("got %+v, want the migrated config", c)
+	}
+	var onDisk C
+	if err := Load(path, &onDisk); err != nil || onDisk != c {
+		t.Errorf("rewritten file holds %+v, %v; want %+v", onDisk, err, c)
+	}
+	if data, err := os.ReadFile(path + ".bak"); err != nil || string(data) != old {
+		t.Errorf("backup %q, %v; want the old file", data, err)
+	}
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schemaDraft is the JSON Schema version generated by Schema.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is the subset of JSON Schema that Schema generates.
+type jsonSchema struct {
+	Schema               string           `json:"$schema,omitempty"`
+	Title                string           `json:"title,omitempty"`
+	Description          string           `json:"description,omitempty"`
+	Type                 any              `json:"type,omitempty"`
+	Format               string           `json:"format,omitempty"`
+	ContentEncoding      string           `json:"contentEncoding,omitempty"`
+	Enum                 []any            `json:"enum,omitempty"`
+	Default              any              `json:"default,omitempty"`
+	Minimum              *float64         `json:"minimum,omitempty"`
+	Maximum              *float64         `json:"maximum,omitempty"`
+	MinLength            *int             `json:"minLength,omitempty"`
+	MaxLength            *int             `json:"maxLength,omitempty"`
+	MinItems             *int             `json:"minItems,omitempty"`
+	MaxItems             *int             `json:"maxItems,omitempty"`
+	MinProperties        *int             `json:"minProperties,omitempty"`
+	MaxProperties        *int             `json:"maxProperties,omitempty"`
+	Items                *jsonSchema      `json:"items,omitempty"`
+	Properties           schemaProperties `json:"properties,omitempty"`
+	AdditionalProperties *jsonSchema      `json:"additionalProperties,omitempty"`
+	Required             []string         `json:"required,omitempty"`
+}
+
+// schemaProperties are the properties of an object schema, in field order.
+type schemaProperties []schemaProperty
+
+type schemaProperty struct {
+	name   string
+	schema *jsonSchema
+}
+
+// MarshalJSON implements json.Marshaler, keeping the field order.
+func (p schemaProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(prop.schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Schema returns a JSON Schema describing the config type of v, which may be
+// a struct, a slice or a map, or a pointer to one. Editors use it for
+// completion and checking. Keys are named as in Validate paths, and each
+// property takes its description from the doc tag, its default from the
+// default tag, and constraints from the validate tag:
+//
+//	required      the key is listed as required and may not be empty
+//	min, max      minimum/maximum, minLength/maxLength, minItems/maxItems or
+//	              minProperties/maxProperties, depending on the type
+//	oneof         enum
+//	url           format "uri"
+//
+// The file, dir and hostport rules and Validate methods can only be checked
+// by Validate.
+func Schema(v any) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, errors.New("config: cannot build a schema for nil")
+	}
+	s := typeSchema(t, map[reflect.Type]bool{})
+	s.Schema = schemaDraft
+	s.Title = typeName(t)
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// typeSchema returns the schema of t. seen holds the struct types being
+// described, so recursive types end in an empty schema.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		// Durations are strings such as "1m30s" in TOML and YAML, and nanoseconds in JSON.
+		return &jsonSchema{Type: []string{"string", "integer"}}
+	case t == reflect.TypeOf(time.Time{}):
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &jsonSchema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &jsonSchema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &jsonSchema{Type: "array", Items: typeSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return &jsonSchema{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		s := &jsonSchema{Type: "object"}
+		addProperties(s, t, seen)
+		return s
+	}
+	return &jsonSchema{}
+}
+
+// addProperties adds the fields of the struct type t to s. Embedded structs
+// are flattened, as by walkFields.
+func addProperties(s *jsonSchema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := fieldKey(sf)
+		if key == "-" {
+			continue
+		}
+		if sf.Anonymous && isNestedType(sf.Type) {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			addProperties(s, ft, seen)
+			continue
+		}
+		prop := typeSchema(sf.Type, seen)
+		prop.Description = sf.Tag.Get("doc")
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			prop.Default = defaultValue(sf.Type, def)
+		}
+		if rules := sf.Tag.Get("validate"); rules != "" {
+			for _, rule := range strings.Split(rules, ",") {
+				applyRule(s, key, prop, sf.Type, strings.TrimSpace(rule))
+			}
+		}
+		s.Properties = append(s.Properties, schemaProperty{name: key, schema: prop})
+	}
+}
+
+// applyRule adds the constraint of a validate rule to prop, the schema of
+// the field key of type t in the object schema parent.
+func applyRule(parent *jsonSchema, key string, prop *jsonSchema, t reflect.Type, rule string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	name, arg, _ := strings.Cut(rule, "=")
+	// Like Validate, oneof and url apply to the elements of a slice.
+	elem, elemType := prop, t
+	if prop.Items != nil {
+		elem, elemType = prop.Items, t.Elem()
+	}
+	switch name {
+	case "required":
+		parent.Required = append(parent.Required, key)
+		setBound(prop, t, "min", 1, true)
+	case "min", "max":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err == nil {
+			setBound(prop, t, name, n, false)
+		}
+	case "oneof":
+		for _, option := range strings.Fields(arg) {
+			elem.Enum = append(elem.Enum, defaultValue(elemType, option))
+		}
+	case "url":
+		elem.Format = "uri"
+	}
+}
+
+// setBound sets the lower or upper bound that fits the kind of t. With
+// lengthOnly, numbers are left unbounded.
+func setBound(s *jsonSchema, t reflect.Type, name string, n float64, lengthOnly bool) {
+	length := int(n)
+	min := name == "min"
+	switch t.Kind() {
+	case reflect.String:
+		if min {
+			s.MinLength = &length
+		} else {
+			s.MaxLength = &length
+		}
+	case reflect.Slice, reflect.Array:
+		if min {
+			s.MinItems = &length
+		} else {
+			s.MaxItems = &length
+		}
+	case reflect.Map:
+		if min {
+			s.MinProperties = &length
+		} else {
+			s.MaxProperties = &length
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if lengthOnly {
+			return
+		}
+		if min {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	}
+}
+
+// defaultValue converts a tag value to the JSON value of type t, or returns
+// it as a string if it cannot be parsed or is written as text in files.
+func defaultValue(t reflect.Type, s string) any {
+	if t == durationType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return s
+	}
+	v := reflect.New(t).Elem()
+	if err := setValue(v, s); err != nil {
+		return s
+	}
+	return v.Interface()
+}
+
+// typeName returns the name of t, or of the element type of an unnamed
+// pointer, slice or map.
+func typeName(t reflect.Type) string {
+	for t.Name() == "" {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return ""
+		}
+	}
+	return t.Name()
+}
+
+// SchemaFlags are the -print-config-schema and -init-config flags of a
+// program, registered by RegisterSchemaFlags.
+type SchemaFlags struct {
+	// PrintSchema requests the JSON Schema of the config on standard output.
+	PrintSchema bool
+	// InitConfig is the path of an example config file to create.
+	InitConfig string
+}
+
+// RegisterSchemaFlags registers the -print-config-schema and -init-config
+// flags on fs. Call Handle after parsing to act on them.
+//
+// Example usage:
+//
+//	schemaFlags := config.RegisterSchemaFlags(flag.CommandLine)
+//	flag.Parse()
+//	if done, err := schemaFlags.Handle(&Config{}); done {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+func RegisterSchemaFlags(fs *flag.FlagSet) *SchemaFlags {
+	f := &SchemaFlags{}
+	fs.BoolVar(&f.PrintSchema, "print-config-schema", false, "Print the JSON Schema of the config file and exit")
+	fs.StringVar(&f.InitConfig, "init-config", "", "Write an example config `file` with all keys documented and exit")
+	return f
+}
+
+// Handle prints the schema or writes the example file for the config v, as
+// requested by the flags, and reports whether it did either. v provides the
+// example values, as for Example.
+func (f *SchemaFlags) Handle(v any) (bool, error) {
+	switch {
+	case f.PrintSchema:
+		data, err := Schema(v)
+		if err != nil {
+			return true, err
+		}
+		_, err = os.Stdout.Write(data)
+		return true, err
+	case f.InitConfig != "":
+		if err := WriteExample(f.InitConfig, v); err != nil {
+			return true, err
+		}
+		fmt.Fprintln(os.Stderr, "Wrote example config to", f.InitConfig)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// schemaServer and schemaConfig exercise the tags that Schema and Example read.
+type schemaServer struct {
+	Host string `json:"host" yaml:"host" toml:"host" doc:"Host name" validate:"required"`
+	Port int    `json:"port" yaml:"port" toml:"port" default:"8080" validate:"min=1,max=65535"`
+}
+
+type schemaConfig struct {
+	Name    string        `json:"name" yaml:"name" toml:"name" doc:"Name of the app" default:"app" validate:"min=2,max=10"`
+	Mode    string        `json:"mode" yaml:"mode" toml:"mode" validate:"oneof=dev prod"`
+	Ratio   float64       `json:"ratio" yaml:"ratio" toml:"ratio" default:"0.5" validate:"max=1"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout" toml:"timeout" default:"1m"`
+	Tags    []string      `json:"tags" yaml:"tags" toml:"tags" validate:"max=3"`
+	Pass    string        `json:"pass" yaml:"pass" toml:"pass" secret:"true"`
+	Server  schemaServer  `json:"server" yaml:"server" toml:"server"`
+	Skip    string        `json:"-" yaml:"-" toml:"-"`
+}
+
+const schemaGolden = `{
+    "$schema": "https://json-schema.org/draft/2020-12/schema",
+    "title": "schemaConfig",
+    "type": "object",
+    "properties": {
+        "name": {
+            "description": "Name of the app",
+            "type": "string",
+            "default": "app",
+            "minLength": 2,
+            "maxLength": 10
+        },
+        "mode": {
+            "type": "string",
+            "enum": [
+                "dev",
+                "prod"
+            ]
+        },
+        "ratio": {
+            "type": "number",
+            "default": 0.5,
+            "maximum": 1
+        },
+        "timeout": {
+            "type": [
+                "string",
+                "integer"
+            ],
+            "default": "1m"
+        },
+        "tags": {
+            "type": "array",
+            "maxItems": 3,
+            "items": {
+                "type": "string"
+            }
+        },
+        "pass": {
+            "type": "string"
+        },
+        "server": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "description": "Host name",
+                    "type": "string",
+                    "minLength": 1
+                },
+                "port": {
+                    "type": "integer",
+                    "default": 8080,
+                    "minimum": 1,
+                    "maximum": 65535
+                }
+            },
+            "required": [
+                "host"
+            ]
+        }
+    }
+}
+`
+
+func TestSchema(t *testing.T) {
+	got, err := Schema(&schemaConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != schemaGolden {
+		t.Errorf("got\n%s\nwant\n%s", got, schemaGolden)
+	}
+	if _, err := Schema(nil); err == nil {
+		t.Error("Schema(nil) did not fail")
+	}
+}
+
+func TestSchemaTypes(t *testing.T) {
+	type Node struct {
+		Name     string  `json:"name"`
+		Children []*Node `json:"children"`
+	}
+	type Base struct {
+		ID int `json:"id" validate:"required"`
+	}
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"bool", struct {
+			On bool `json:"on" default:"true"`
+		}{}, `{"on":{"type":"boolean","default":true}}`},
+		{"unsigned", struct {
+			N uint8 `json:"n" validate:"required,max=9"`
+		}{}, `{"n":{"type":"integer","maximum":9}}`},
+		{"bytes", struct {
+			Key []byte `json:"key"`
+		}{}, `{"key":{"type":"string","contentEncoding":"base64"}}`},
+		{"time", struct {
+			At time.Time `json:"at"`
+		}{}, `{"at":{"type":"string","format":"date-time"}}`},
+		{"url elements", struct {
+			URLs []string `json:"urls" validate:"min=1,url"`
+		}{}, `{"urls":{"type":"array","minItems":1,"items":{"type":"string","format":"uri"}}}`},
+		{"oneof elements", struct {
+			Levels []int `json:"levels" validate:"oneof=1 2"`
+		}{}, `{"levels":{"type":"array","items":{"type":"integer","enum":[1,2]}}}`},
+		{"map", struct {
+			Env map[string]string `json:"env" validate:"min=1"`
+		}{}, `{"env":{"type":"object","minProperties":1,"additionalProperties":{"type":"string"}}}`},
+		{"pointer", struct {
+			Opt *struct {
+				A string `json:"a"`
+			} `json:"opt"`
+		}{}, `{"opt":{"type":"object","properties":{"a":{"type":"string"}}}}`},
+		{"recursive", Node{}, `{"name":{"type":"string"},"children":{"type":"array","items":{}}}`},
+		{"embedded", struct {
+			Base
+			Name string `json:"name"`
+		}{}, `{"id":{"type":"integer"},"name":{"type":"string"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Schema(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s struct {
+				Properties json.RawMessage `json:"properties"`
+			}
+			if err := json.Unmarshal(data, &s); err != nil {
+				t.Fatal(err)
+			}
+			var got, want any
+			json.Unmarshal(s.Properties, &got)
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("properties %s, want %s", s.Properties, tt.want)
+			}
+		})
+	}
+}
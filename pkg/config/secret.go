@@ -0,0 +1,267 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Prefixes of secret references. Values without one of them are used as is.
+const (
+	secretFile = "file:"
+	secretEnv  = "env:"
+	secretEnc  = "enc:"
+)
+
+// keySize is the size of secret keys, for AES-256.
+const keySize = 32
+
+// ErrNoKey is returned when an enc: secret is found but the key file does not exist.
+var ErrNoKey = errors.New("no secret key")
+
+// WithKeyFile sets the key file used to decrypt enc: secrets. It defaults
+// to DefaultKeyFile.
+func WithKeyFile(path string) Option {
+	return func(l *Loader) {
+		l.keyFile = path
+	}
+}
+
+// DefaultKeyFile returns the key file used when WithKeyFile is not given:
+// $CONFIG_KEY_FILE if it is set, otherwise config.key in a merith-tk
+// directory under os.UserConfigDir.
+func DefaultKeyFile() string {
+	if path := os.Getenv("CONFIG_KEY_FILE"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "config.key"
+	}
+	return filepath.Join(dir, "merith-tk", "config.key")
+}
+
+// GenerateKey returns a new random secret key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewKeyFile generates a key and stores it base64-encoded in a new file at
+// path, readable only by the owner. It fails if the file already exists, so
+// a key that secrets were encrypted with is never replaced.
+func NewKeyFile(path string) ([]byte, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return key, f.Close()
+}
+
+// ReadKeyFile reads a key written by NewKeyFile.
+func ReadKeyFile(path string) ([]byte, error) {
+	key, err := readKeyFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return key, nil
+}
+
+// readKeyFile implements ReadKeyFile without the package prefix in errors.
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s does not exist", ErrNoKey, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("%s is not a valid key file", path)
+	}
+	return key, nil
+}
+
+// EncryptSecret encrypts plaintext with AES-GCM and returns it as an enc:
+// reference for a config file.
+func EncryptSecret(plaintext string, key []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", fmt.Errorf("config: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretEnc + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts an enc: reference made by EncryptSecret.
+func DecryptSecret(value string, key []byte) (string, error) {
+	plaintext, err := decryptSecret(value, key)
+	if err != nil {
+		return "", fmt.Errorf("config: %w", err)
+	}
+	return plaintext, nil
+}
+
+// decryptSecret implements DecryptSecret without the package prefix in errors.
+func decryptSecret(value string, key []byte) (string, error) {
+	data, ok := strings.CutPrefix(value, secretEnc)
+	if !ok {
+		return "", errors.New("not an enc: secret")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", errors.New("enc: secret is not valid base64")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("enc: secret is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("enc: secret cannot be decrypted with this key")
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD returns the AES-GCM cipher for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("secret key must be %d bytes", keySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ResolveSecret returns the value a secret reference stands for:
+//
+//	file:/path  the content of the file, without a trailing newline
+//	env:NAME    the environment variable NAME, which must be set
+//	enc:...     the value decrypted with the key file (see WithKeyFile)
+//
+// Any other value is returned unchanged, so plain values keep working.
+func ResolveSecret(value string, opts ...Option) (string, error) {
+	secret, err := NewLoader(opts...).resolveSecret(value)
+	if err != nil {
+		return "", fmt.Errorf("config: secret: %w", err)
+	}
+	return secret, nil
+}
+
+// ResolveSecrets replaces the secret references in the fields of target
+// that are tagged `secret:"true"`, which must be strings or string slices.
+// Nil struct pointers are left nil. Load and Loader.Load call it before
+// validating.
+//
+// Example usage:
+//
+//	type Config struct {
+//		Pass string `json:"pass" secret:"true"` // "env:SMTP_PASS", "file:/run/secrets/smtp" or "enc:..."
+//	}
+func ResolveSecrets(target any, opts ...Option) error {
+	return NewLoader(opts...).resolveSecrets(target)
+}
+
+// resolveSecrets implements ResolveSecrets with the options of l. The key
+// file is only read if an enc: secret is found.
+func (l *Loader) resolveSecrets(target any) error {
+	return walkSetFields(target, func(f field) error {
+		if f.tag("secret") != "true" {
+			return nil
+		}
+		switch {
+		case f.value.Kind() == reflect.String:
+			return l.resolveSecretValue(f.path, f.value)
+		case f.value.Kind() == reflect.Slice && f.value.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < f.value.Len(); i++ {
+				if err := l.resolveSecretValue(fmt.Sprintf("%s[%d]", f.path, i), f.value.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return fmt.Errorf("config: secret %s must be a string, not %s", f.path, f.value.Type())
+		}
+	})
+}
+
+// resolveSecretValue resolves the string in v, naming path in errors.
+func (l *Loader) resolveSecretValue(path string, v reflect.Value) error {
+	value, err := l.resolveSecret(v.String())
+	if err != nil {
+		return fmt.Errorf("config: secret %s: %w", path, err)
+	}
+	v.SetString(value)
+	return nil
+}
+
+// resolveSecret implements ResolveSecret. Errors never contain the secret.
+func (l *Loader) resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretFile):
+		data, err := os.ReadFile(strings.TrimPrefix(value, secretFile))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, secretEnv):
+		name := strings.TrimPrefix(value, secretEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, secretEnc):
+		if l.secretKey == nil {
+			keyFile := l.keyFile
+			if keyFile == "" {
+				keyFile = DefaultKeyFile()
+			}
+			key, err := readKeyFile(keyFile)
+			if err != nil {
+				return "", err
+			}
+			l.secretKey = key
+		}
+		return decryptSecret(value, l.secretKey)
+	}
+	return value, nil
+}
+
+// isStructPointer reports whether v is a non-nil pointer to a struct.
+func isStructPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSecretRoundTrip(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range []string{"", "hunter2", "línea\nwith\x00bytes"} {
+		enc, err := EncryptSecret(plaintext, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(enc, "enc:") || (plaintext != "" && strings.Contains(enc, plaintext)) {
+			t.Errorf("EncryptSecret(%q) = %q", plaintext, enc)
+		}
+		got, err := DecryptSecret(enc, key)
+		if err != nil || got != plaintext {
+			t.Errorf("DecryptSecret = %q, %v; want %q", got, err, plaintext)
+		}
+		if again, _ := EncryptSecret(plaintext, key); again == enc {
+			t.Errorf("EncryptSecret(%q) reused its nonce", plaintext)
+		}
+	}
+}
+
+func TestDecryptSecretErrors(t *testing.T) {
+	key, _ := GenerateKey()
+	other, _ := GenerateKey()
+	enc, err := EncryptSecret("hunter2", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := []byte(enc)
+	sealed[len(sealed)-3] ^= 1
+	tests := []struct {
+		name  string
+		value string
+		key   []byte
+		err   string
+	}{
+		{"wrong key", enc, other, "cannot be decrypted"},
+		{"tampered", string(sealed), key, "cannot be decrypted"},
+		{"short key", enc, key[:16], "must be 32 bytes"},
+		{"no prefix", "hunter2", key, "not an enc: secret"},
+		{"bad base64", "enc:!!", key, "not valid base64"},
+		{"too short", "enc:AAAA", key, "too short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptSecret(tt.value, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %q, %v; want an error containing %q", got, err, tt.err)
+			}
+			if strings.Contains(err.Error(), "hunter2") {
+				t.Errorf("error %q contains the secret", err)
+			}
+		})
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "config.key")
+	key, err := NewKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadKeyFile(path); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ReadKeyFile = %x, %v; want %x", got, err, key)
+	}
+	if _, err := NewKeyFile(path); !errors.Is(err, os.ErrExist) {
+		t.Errorf("NewKeyFile over an existing key: got %v, want os.ErrExist", err)
+	}
+	if _, err := ReadKeyFile(filepath.Join(dir, "missing.key")); !errors.Is(err, ErrNoKey) {
+		t.Errorf("missing key file: got %v, want ErrNoKey", err)
+	}
+	bad := filepath.Join(dir, "bad.key")
+	os.WriteFile(bad, []byte("c2hvcnQ=\n"), 0600)
+	if _, err := ReadKeyFile(bad); err == nil || !strings.Contains(err.Error(), "not a valid key file") {
+		t.Errorf("short key file: got %v", err)
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "config.key")
+	key, err := NewKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeyFile := filepath.Join(dir, "other.key")
+	if _, err := NewKeyFile(otherKeyFile); err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := EncryptSecret("from-enc", key)
+	secretFile := filepath.Join(dir, "secret")
+	os.WriteFile(secretFile, []byte("from-file\r\n"), 0600)
+	t.Setenv("TEST_CONFIG_SECRET", "from-env")
+
+	tests := []struct {
+		name  string
+		value string
+		opts  []Option
+		want  string
+		err   error
+		msg   string
+	}{
+		{"plain", "plain", nil, "plain", nil, ""},
+		{"file", "file:" + secretFile, nil, "from-file", nil, ""},
+		{"missing file", "file:" + filepath.Join(dir, "missing"), nil, "", os.ErrNotExist, ""},
+		{"env", "env:TEST_CONFIG_SECRET", nil, "from-env", nil, ""},
+		{"unset env", "env:TEST_CONFIG_UNSET", nil, "", nil, "TEST_CONFIG_UNSET is not set"},
+		{"enc", enc, []Option{WithKeyFile(keyFile)}, "from-enc", nil, ""},
+		{"enc without key file", enc, []Option{WithKeyFile(filepath.Join(dir, "missing.key"))}, "", ErrNoKey, ""},
+		{"enc with wrong key", enc, []Option{WithKeyFile(otherKeyFile)}, "", nil, "cannot be decrypted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecret(tt.value, tt.opts...)
+			switch {
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %q, %v; want %v", got, err, tt.err)
+				}
+			case tt.msg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.msg) {
+					t.Fatalf("got %q, %v; want an error containing %q", got, err, tt.msg)
+				}
+			case err != nil || got != tt.want:
+				t.Fatalf("got %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("TEST_CONFIG_A", "a")
+	t.Setenv("TEST_CONFIG_B", "b")
+	type C struct {
+		Pass   string   `secret:"true"`
+		Tokens []string `secret:"true"`
+		Plain  string
+	}
+	c := C{Pass: "env:TEST_CONFIG_A", Tokens: []string{"env:TEST_CONFIG_B", "literal"}, Plain: "env:TEST_CONFIG_A"}
+	if err := ResolveSecrets(&c); err != nil {
+		t.Fatal(err)
+	}
+	want := C{Pass: "a", Tokens: []string{"b", "literal"}, Plain: "env:TEST_CONFIG_A"}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	c = C{Tokens: []string{"literal", "env:TEST_CONFIG_UNSET"}}
+	if err := ResolveSecrets(&c); err == nil || !strings.Contains(err.Error(), "tokens[1]") {
+		t.Errorf("got %v, want an error naming tokens[1]", err)
+	}
+	var bad struct {
+		Port int `secret:"true"`
+	}
+	if err := ResolveSecrets(&bad); err == nil || !strings.Contains(err.Error(), "must be a string") {
+		t.Errorf("got %v, want an error for a non-string secret", err)
+	}
+}
+
+func TestLoadKeepsNilSections(t *testing.T) {
+	type Sub struct {
+		Pass string `toml:"pass" secret:"true"`
+	}
+	type C struct {
+		Name string `toml:"name"`
+		Opt  *Sub   `toml:"opt"`
+		Auth *Sub   `toml:"auth"`
+	}
+	t.Setenv("TEST_CONFIG_PASS", "hunter2")
+	path := filepath.Join(t.TempDir(), "c.toml")
+	data := "name = \"x\"\n[auth]\npass = \"env:TEST_CONFIG_PASS\"\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var c C
+	if err := Load(path, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Opt != nil {
+		t.Errorf("absent section opt = %+v, want nil", c.Opt)
+	}
+	if c.Auth == nil || c.Auth.Pass != "hunter2" {
+		t.Errorf("auth = %+v, want the resolved secret", c.Auth)
+	}
+}
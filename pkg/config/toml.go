@@ -1,32 +1,15 @@
 package config
 
-import (
-	"os"
-	"path/filepath"
-
-	"github.com/BurntSushi/toml"
-)
-
 // LoadToml loads and parses the config file at the given path into the provided struct pointer.
-// The config file must be in TOML format.
+// The config file must be in TOML format regardless of its extension; see Load for other formats.
+// It only decodes the file: unlike Load it neither resolves secret references nor validates the
+// result, so partial files and literal "enc:" or "file:" values load as they are.
 func LoadToml(target interface{}, configfile string) error {
-	configfile = filepath.ToSlash(configfile)
-	if _, err := os.Stat(configfile); os.IsNotExist(err) {
-		return err
-	}
-	str, err := os.ReadFile(configfile)
-	if err != nil {
-		return err
-	}
-	return toml.Unmarshal([]byte(str), target)
+	return NewLoader(WithFormat("toml")).decodeFile(configfile, target)
 }
 
-// SaveToml saves a struct as TOML to the given file path.
+// SaveToml saves a struct as TOML to the given file path, regardless of its extension.
+// The file is replaced atomically as described for WriteFile.
 func SaveToml(path string, cfg interface{}) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	return toml.NewEncoder(f).Encode(cfg)
+	return Save(path, cfg, WithFormat("toml"))
 }
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTomlOnlyDecodes(t *testing.T) {
+	type C struct {
+		Name string `toml:"name" validate:"required"`
+		Pass string `toml:"pass" secret:"true"`
+	}
+	path := filepath.Join(t.TempDir(), "c.conf")
+	if err := os.WriteFile(path, []byte("pass = \"enc:not-base64\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var c C
+	if err := LoadToml(&c, path); err != nil {
+		t.Fatal(err)
+	}
+	if c.Pass != "enc:not-base64" {
+		t.Errorf("pass = %q, want the literal value", c.Pass)
+	}
+	if err := Load(path, &c, WithFormat("toml")); err == nil {
+		t.Error("Load accepted the file, want secret and validation errors")
+	}
+}
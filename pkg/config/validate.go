@@ -0,0 +1,324 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Validator is implemented by config types that check themselves, e.g. for
+// rules that span several fields. Validate calls it after the tag rules, on
+// the top-level value and on every nested struct.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError is a single failed rule. Path is the key path of the field in
+// the config file, such as "notify.email.server" or "servers[1]".
+type FieldError struct {
+	Path string
+	Rule string
+	Err  error
+}
+
+// Error implements error.
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError holds every failed rule of a config. File is the config
+// file it was loaded from, if known.
+type ValidationError struct {
+	File   string
+	Errors []*FieldError
+}
+
+// Error implements error. Each field error is on its own line.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("config: ")
+	if e.File != "" {
+		b.WriteString(e.File + ": ")
+	}
+	b.WriteString("invalid configuration")
+	for _, fe := range e.Errors {
+		b.WriteString("\n\t" + fe.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the field errors, so errors.As finds a *FieldError.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// Validate checks v against the `validate` struct tags of its fields and the
+// Validate methods of v and its nested structs. It returns a
+// *ValidationError listing every failure, or nil. Rules are separated by
+// commas:
+//
+//	required    the value is not empty
+//	min=N       numbers are at least N; strings, slices and maps have at least N elements
+//	max=N       numbers are at most N; strings, slices and maps have at most N elements
+//	oneof=a b   the value is one of the space-separated words
+//	url         the value is an absolute URL with a scheme and host
+//	hostport    the value is a host:port address
+//	file        the value is the path of an existing regular file
+//	dir         the value is the path of an existing directory
+//
+// The min and max rules also apply to zero numbers and empty strings, slices
+// and maps, so "min=1" requires a value; the other rules skip empty values,
+// and all rules skip nil pointers. The oneof, url, hostport, file and dir
+// rules are applied to each element of a slice.
+func Validate(v any) error {
+	var errs []*FieldError
+	validateValue(reflect.ValueOf(v), "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// WithoutValidation makes Load and Loader.Load skip Validate, e.g. for
+// internal files that embed user configs, which are checked when they are
+// used.
+func WithoutValidation() Option {
+	return func(l *Loader) {
+		l.noValidate = true
+	}
+}
+
+// validateFile validates v, unless WithoutValidation was given, and records
+// the config file in the error.
+func (l *Loader) validateFile(file string, v any) error {
+	if l.noValidate {
+		return nil
+	}
+	err := Validate(v)
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		ve.File = file
+	}
+	return err
+}
+
+// validateValue walks v, checking nested structs, slices and maps.
+func validateValue(v reflect.Value, path string, errs *[]*FieldError) {
+	if !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			validateValue(v.Elem(), path, errs)
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+		return
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			validateValue(iter.Value(), joinPath(path, fmt.Sprint(iter.Key().Interface())), errs)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := fieldKey(sf)
+		if key == "-" {
+			continue
+		}
+		fpath := joinPath(path, key)
+		if sf.Anonymous {
+			fpath = path
+		}
+		fv := v.Field(i)
+		if rules := sf.Tag.Get("validate"); rules != "" {
+			for _, rule := range strings.Split(rules, ",") {
+				if err := checkRule(fv, strings.TrimSpace(rule)); err != nil {
+					*errs = append(*errs, &FieldError{Path: fpath, Rule: rule, Err: err})
+				}
+			}
+		}
+		validateValue(fv, fpath, errs)
+	}
+
+	if validator, ok := asValidator(v); ok {
+		if err := validator.Validate(); err != nil {
+			*errs = append(*errs, &FieldError{Path: path, Rule: "Validate", Err: err})
+		}
+	}
+}
+
+// asValidator returns v, or a pointer to it, as a Validator.
+func asValidator(v reflect.Value) (Validator, bool) {
+	if v.CanAddr() {
+		if validator, ok := v.Addr().Interface().(Validator); ok {
+			return validator, true
+		}
+	}
+	if v.CanInterface() {
+		validator, ok := v.Interface().(Validator)
+		return validator, ok
+	}
+	return nil, false
+}
+
+// joinPath appends key to the dotted path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// checkRule checks a single rule such as "min=1" against v.
+func checkRule(v reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	if name == "required" {
+		if isEmpty(v) {
+			return errors.New("is required")
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Errorf("invalid rule %q", rule)
+		}
+		n, isLen := measure(v)
+		if name == "min" && n < limit {
+			if isLen {
+				return fmt.Errorf("length must be at least %s", arg)
+			}
+			return fmt.Errorf("must be at least %s", arg)
+		}
+		if name == "max" && n > limit {
+			if isLen {
+				return fmt.Errorf("length must be at most %s", arg)
+			}
+			return fmt.Errorf("must be at most %s", arg)
+		}
+		return nil
+	case "oneof", "url", "hostport", "file", "dir":
+		if isEmpty(v) {
+			return nil
+		}
+		return eachString(v, func(s string) error { return checkString(name, arg, s) })
+	default:
+		return fmt.Errorf("unknown rule %q", rule)
+	}
+}
+
+// checkString applies a string rule to s.
+func checkString(name, arg, s string) error {
+	switch name {
+	case "oneof":
+		options := strings.Fields(arg)
+		for _, option := range options {
+			if s == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be one of %s", s, strings.Join(options, ", "))
+	case "url":
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%q is not an absolute URL", s)
+		}
+	case "hostport":
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			return fmt.Errorf("%q is not a host:port address", s)
+		}
+	case "file":
+		info, err := os.Stat(s)
+		if err != nil {
+			return fmt.Errorf("file %q does not exist", s)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%q is not a regular file", s)
+		}
+	case "dir":
+		info, err := os.Stat(s)
+		if err != nil {
+			return fmt.Errorf("directory %q does not exist", s)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%q is not a directory", s)
+		}
+	}
+	return nil
+}
+
+// eachString calls fn with the string form of v, or of each element if v is a slice.
+func eachString(v reflect.Value, fn func(string) error) error {
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		var errs []error
+		for i := 0; i < v.Len(); i++ {
+			if err := fn(fmt.Sprint(v.Index(i).Interface())); err != nil {
+				errs = append(errs, fmt.Errorf("[%d]: %w", i, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return fn(fmt.Sprint(v.Interface()))
+}
+
+// measure returns the number to compare for min and max, and whether it is a length.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}
+
+// isEmpty reports whether v is the zero value or an empty string, slice or map.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
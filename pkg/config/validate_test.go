@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestValidateMinMax(t *testing.T) {
+	type C struct {
+		Servers []string `validate:"min=1"`
+		Port    int      `validate:"min=1,max=65535"`
+		Name    string   `validate:"max=3"`
+		Limit   *int     `validate:"min=1"`
+		Mode    string   `validate:"oneof=fast safe"`
+	}
+	zero, big := 0, 70000
+	tests := []struct {
+		name  string
+		c     C
+		paths []string
+	}{
+		{"valid", C{Servers: []string{"a"}, Port: 80, Name: "abc"}, nil},
+		{"empty slice and zero number", C{}, []string{"servers", "port"}},
+		{"too large", C{Servers: []string{"a"}, Port: big, Name: "abcd"}, []string{"port", "name"}},
+		{"pointer to zero", C{Servers: []string{"a"}, Port: 80, Limit: &zero}, []string{"limit"}},
+		{"invalid oneof", C{Servers: []string{"a"}, Port: 80, Mode: "slow"}, []string{"mode"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.c)
+			var paths []string
+			var ve *ValidationError
+			if errors.As(err, &ve) {
+				for _, fe := range ve.Errors {
+					paths = append(paths, fe.Path)
+				}
+			} else if err != nil {
+				t.Fatalf("got %v, want a *ValidationError", err)
+			}
+			if !slices.Equal(paths, tt.paths) {
+				t.Errorf("failed fields %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestLoadWithoutValidation(t *testing.T) {
+	type C struct {
+		Name string `json:"name" validate:"required"`
+	}
+	path := filepath.Join(t.TempDir(), "c.json")
+	if err := os.WriteFile(path, []byte(`{"name": ""}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var c C
+	var ve *ValidationError
+	if err := Load(path, &c); !errors.As(err, &ve) {
+		t.Fatalf("Load: got %v, want a *ValidationError", err)
+	}
+	if err := Load(path, &c, WithoutValidation()); err != nil {
+		t.Errorf("Load with WithoutValidation: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay is how long a Watcher waits after the last change event before
+// reloading, so that editors which write a file in several steps cause a
+// single reload.
+const reloadDelay = 100 * time.Millisecond
+
+// Watcher keeps a config loaded from a file up to date. Each reload parses
+// and validates the file into a new value and swaps it in atomically, so
+// readers always see a complete config. If the new file is invalid the
+// previous config is kept. It is safe for concurrent use.
+type Watcher[T any] struct {
+	loader   *Loader
+	path     string
+	onChange func(cfg *T, err error)
+	current  atomic.Pointer[T]
+
+	mu        sync.Mutex // serializes reloads
+	fsw       *fsnotify.Watcher
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Watch loads the file at path into target like Loader.Load, with opts
+// applied, and then reloads it whenever the file changes. onChange, which
+// may be nil, is called after every reload: with the new config, or with
+// the config that is kept and the error if the file could not be loaded.
+// Reloads that produce an identical config are not reported.
+//
+// target holds the initial config and is what Get returns until the first
+// change; it must not be modified afterwards. Call Close to stop watching.
+//
+// Example usage:
+//
+//	var cfg Config
+//	w, err := config.Watch("servers.json", &cfg, func(c *Config, err error) {
+//		if err != nil {
+//			debug.Warn("keeping previous config", "err", err)
+//			return
+//		}
+//		debug.Info("config reloaded", "servers", len(c.Servers))
+//	})
+//	...
+//	current := w.Get()
+func Watch[T any](path string, target *T, onChange func(cfg *T, err error), opts ...Option) (*Watcher[T], error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	loader := NewLoader(opts...)
+	loader.files = append(loader.files, loaderFile{path: abs})
+	if err := loader.Load(target); err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Watch the directory, as editors often replace the file by renaming.
+	if err := fsw.Add(filepath.Dir(abs)); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	w := &Watcher[T]{
+		loader:   loader,
+		path:     abs,
+		onChange: onChange,
+		fsw:      fsw,
+		done:     make(chan struct{}),
+	}
+	w.current.Store(target)
+	go w.run()
+	return w, nil
+}
+
+// Get returns the current config. The returned value must not be modified.
+func (w *Watcher[T]) Get() *T {
+	return w.current.Load()
+}
+
+// Reload loads the file now. On success the new config replaces the current
+// one; otherwise the current one is kept. onChange is called in both cases,
+// unless the config did not change.
+func (w *Watcher[T]) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	next := new(T)
+	if err := w.loader.Load(next); err != nil {
+		w.notify(w.Get(), err)
+		return err
+	}
+	prev := w.current.Swap(next)
+	if !reflect.DeepEqual(prev, next) {
+		w.notify(next, nil)
+	}
+	return nil
+}
+
+// Close stops watching the file. The current config stays available through Get.
+func (w *Watcher[T]) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+// run reloads the file after changes until the watcher is closed.
+func (w *Watcher[T]) run() {
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != w.path || event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.notify(w.Get(), err)
+		case <-timer.C:
+			w.Reload()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// notify calls onChange if it is set.
+func (w *Watcher[T]) notify(cfg *T, err error) {
+	if w.onChange != nil {
+		w.onChange(cfg, err)
+	}
+}
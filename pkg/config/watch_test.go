@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	type C struct {
+		Name string `json:"name" validate:"required"`
+	}
+	type change struct {
+		cfg *C
+		err error
+	}
+	path := filepath.Join(t.TempDir(), "app.json")
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	next := func(changes chan change) change {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload")
+			return change{}
+		}
+	}
+	write(`{"name": "first"}`)
+
+	changes := make(chan change, 10)
+	var cfg C
+	w, err := Watch(path, &cfg, func(c *C, err error) { changes <- change{c, err} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.Get() != &cfg || cfg.Name != "first" {
+		t.Fatalf("initial config %+v", w.Get())
+	}
+
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+		err    bool
+	}{
+		{"reload", []string{`{"name": "second"}`}, "second", false},
+		{"rapid writes reload once", []string{`{"name": "a"}`, `{"name": "b"}`, `{"name": "third"}`}, "third", false},
+		{"invalid syntax keeps config", []string{`{"name": `}, "third", true},
+		{"failed validation keeps config", []string{`{"name": ""}`}, "third", true},
+		{"recovers", []string{`{"name": "fourth"}`}, "fourth", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, data := range tt.writes {
+				write(data)
+			}
+			c := next(changes)
+			if (c.err != nil) != tt.err || c.cfg.Name != tt.want {
+				t.Errorf("onChange(%+v, %v), want name %q and error %v", c.cfg, c.err, tt.want, tt.err)
+			}
+			if got := w.Get().Name; got != tt.want {
+				t.Errorf("Get().Name = %q, want %q", got, tt.want)
+			}
+			select {
+			case c := <-changes:
+				t.Errorf("extra onChange(%+v, %v)", c.cfg, c.err)
+			case <-time.After(3 * reloadDelay):
+			}
+		})
+	}
+
+	// Writing the same config again does not report a change.
+	write(`{"name": "fourth"}`)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		t.Errorf("unchanged config reported: %+v, %v", c.cfg, c.err)
+	case <-time.After(3 * reloadDelay):
+	}
+
+	w.Close()
+	write(`{"name": "closed"}`)
+	select {
+	case c := <-changes:
+		t.Errorf("reload after Close: %+v, %v", c.cfg, c.err)
+	case <-time.After(3 * reloadDelay):
+	}
+}
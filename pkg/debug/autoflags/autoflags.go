@@ -0,0 +1,17 @@
+// Package autoflags registers the debug package's flags on flag.CommandLine
+// when imported, restoring the behavior of older versions of pkg/debug:
+//
+//	import _ "github.com/Merith-TK/utils/pkg/debug/autoflags"
+//
+// New code should call debug.RegisterFlags(flag.CommandLine) before flag.Parse instead.
+package autoflags
+
+import (
+	"flag"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+)
+
+func init() {
+	debug.RegisterFlags(flag.CommandLine)
+}
@@ -0,0 +1,166 @@
+package debug
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// packagePrefix identifies frames that belong to this package. Sub-packages
+// use a "/" after the package path and are therefore not matched.
+const packagePrefix = "github.com/Merith-TK/utils/pkg/debug."
+
+// DefaultStackDepth is the number of frames captured for a stack trace.
+const DefaultStackDepth = 32
+
+// Frame is a single resolved stack frame.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Short returns the frame's location as "dir/file.go:line".
+func (f Frame) Short() string {
+	if f.File == "" {
+		return ""
+	}
+	dir, file := path.Split(f.File)
+	return path.Base(dir) + "/" + file + ":" + fmt.Sprint(f.Line)
+}
+
+// String returns the frame as "function (file:line)".
+func (f Frame) String() string {
+	return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+var (
+	// stackDepth is the number of frames captured for stack traces.
+	stackDepth = DefaultStackDepth
+	// enableCaller indicates if records are annotated with their caller.
+	enableCaller = true
+)
+
+// SetStackDepth sets how many frames are captured for stack traces.
+// Values below 1 restore DefaultStackDepth.
+func SetStackDepth(depth int) {
+	if depth < 1 {
+		depth = DefaultStackDepth
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	stackDepth = depth
+}
+
+// GetStackDepth returns how many frames are captured for stack traces.
+func GetStackDepth() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return stackDepth
+}
+
+// SetCaller enables or disables the file:line annotation on every message.
+// It is enabled by default.
+func SetCaller(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	enableCaller = enabled
+}
+
+// GetCaller returns true if messages are annotated with their caller.
+func GetCaller() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enableCaller
+}
+
+// callers captures up to depth frames of the calling goroutine, starting at
+// the first frame outside this package.
+func callers(depth int) []Frame {
+	pcs := make([]uintptr, depth+16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var out []Frame
+	for len(out) < depth {
+		frame, more := frames.Next()
+		if !(len(out) == 0 && strings.HasPrefix(frame.Function, packagePrefix)) && frame.Function != "runtime.goexit" {
+			out = append(out, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// caller returns the first frame outside this package.
+func caller() (Frame, bool) {
+	frames := callers(1)
+	if len(frames) == 0 {
+		return Frame{}, false
+	}
+	return frames[0], true
+}
+
+// StackError is an error that records the stack at the point it was created.
+// Print it with "%+v" to include the stack, or log it as a field value and
+// the stack is attached to the record.
+type StackError struct {
+	err   error
+	stack []Frame
+}
+
+// Errorf formats an error like fmt.Errorf and attaches the caller's stack.
+// If the wrapped error (%w) already carries a stack, that original stack is kept.
+func Errorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	var inner *StackError
+	if errors.As(err, &inner) {
+		return &StackError{err: err, stack: inner.stack}
+	}
+	return &StackError{err: err, stack: callers(GetStackDepth())}
+}
+
+// Error implements error.
+func (e *StackError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error created by fmt.Errorf, so errors.Is and errors.As
+// see any error wrapped with %w.
+func (e *StackError) Unwrap() error {
+	return e.err
+}
+
+// StackTrace returns the frames captured when the error was created.
+func (e *StackError) StackTrace() []Frame {
+	return e.stack
+}
+
+// Format implements fmt.Formatter. The "%+v" verb prints the message
+// followed by one line per frame.
+func (e *StackError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Error())
+		for _, f := range e.stack {
+			io.WriteString(s, "\n\t"+f.Function+"\n\t\t"+f.File+":"+fmt.Sprint(f.Line))
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// StackOf returns the stack attached to err or any error it wraps, or nil.
+func StackOf(err error) []Frame {
+	var se *StackError
+	if errors.As(err, &se) {
+		return se.stack
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package debug_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+	"github.com/Merith-TK/utils/pkg/debug/debugtest"
+)
+
+// here returns the file and line of its caller.
+func here() (string, int) {
+	_, file, line, _ := runtime.Caller(1)
+	return file, line
+}
+
+// makeError returns an error from debug.Errorf and the line it was created on.
+func makeError() (error, int) {
+	_, line := here()
+	return debug.Errorf("open config: %w", fs.ErrNotExist), line + 1
+}
+
+func TestErrorfCaller(t *testing.T) {
+	file, _ := here()
+	err, line := makeError()
+
+	stack := debug.StackOf(err)
+	if len(stack) < 2 {
+		t.Fatalf("stack %v, want at least makeError and its caller", stack)
+	}
+	if stack[0].File != file || stack[0].Line != line || !strings.HasSuffix(stack[0].Function, ".makeError") {
+		t.Errorf("first frame %v, want makeError at %s:%d", stack[0], file, line)
+	}
+	if want := "debug/caller_test.go:" + fmt.Sprint(line); stack[0].Short() != want {
+		t.Errorf("Short() = %q, want %q", stack[0].Short(), want)
+	}
+	if !strings.HasSuffix(stack[1].Function, ".TestErrorfCaller") {
+		t.Errorf("second frame %v, want the test", stack[1])
+	}
+	if !errors.Is(err, fs.ErrNotExist) || err.Error() != "open config: file does not exist" {
+		t.Errorf("error %q does not wrap fs.ErrNotExist", err)
+	}
+
+	// Wrapping keeps the stack of the original error.
+	wrapped := debug.Errorf("load: %w", err)
+	if got := debug.StackOf(wrapped); len(got) == 0 || got[0] != stack[0] {
+		t.Errorf("wrapped stack starts at %v, want %v", got, stack[0])
+	}
+	verbose := fmt.Sprintf("%+v", wrapped)
+	if want := "load: open config: file does not exist\n\t"; !strings.HasPrefix(verbose, want) ||
+		!strings.Contains(verbose, fmt.Sprintf("%s:%d", file, line)) {
+		t.Errorf("%%+v = %q, want the message and the stack", verbose)
+	}
+	if got := fmt.Sprintf("%v|%q", wrapped, wrapped); got != `load: open config: file does not exist|"load: open config: file does not exist"` {
+		t.Errorf("%%v|%%q = %s", got)
+	}
+	if debug.StackOf(errors.New("plain")) != nil {
+		t.Error("StackOf returned a stack for a plain error")
+	}
+}
+
+func TestRecordCaller(t *testing.T) {
+	file, _ := here()
+	logger := debug.New("caller")
+	tests := []struct {
+		name string
+		log  func() int
+	}{
+		{"Info", func() int { _, line := here(); debug.Info("msg"); return line }},
+		{"Warn", func() int { _, line := here(); debug.Warn("msg"); return line }},
+		{"Logger", func() int { _, line := here(); logger.Error("msg"); return line }},
+		{"Print", func() int { _, line := here(); debug.Print("msg"); return line }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := debugtest.CaptureAt(t, debug.LevelDebug)
+			line := tt.log()
+			got := rec.Expect(debugtest.Msg("msg")).Caller
+			if got.File != file || got.Line != line {
+				t.Errorf("caller %s:%d, want %s:%d", filepath.Base(got.File), got.Line, filepath.Base(file), line)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		rec := debugtest.Capture(t)
+		debug.SetCaller(false)
+		defer debug.SetCaller(true)
+		debug.Info("msg")
+		if got := rec.Expect(debugtest.Msg("msg")).Caller; got != (debug.Frame{}) {
+			t.Errorf("caller %v with SetCaller(false)", got)
+		}
+	})
+
+	t.Run("error field stack", func(t *testing.T) {
+		rec := debugtest.Capture(t)
+		err, line := makeError()
+		debug.Error("failed", "err", err)
+		stack := rec.Expect(debugtest.Msg("failed")).Stack
+		if len(stack) == 0 || stack[0].Line != line {
+			t.Errorf("record stack %v, want the stack of the error", stack)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package debug
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileCreated returns the creation time of the file at path, or its
+// modification time if it is unknown.
+func fileCreated(path string, info os.FileInfo) time.Time {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(st.Birthtimespec.Unix())
+}
@@ -0,0 +1,19 @@
+package debug
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileCreated returns the creation time of the file at path, or its
+// modification time if the file system does not record one.
+func fileCreated(path string, info os.FileInfo) time.Time {
+	var st unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &st)
+	if err != nil || st.Mask&unix.STATX_BTIME == 0 {
+		return info.ModTime()
+	}
+	return time.Unix(st.Btime.Sec, int64(st.Btime.Nsec))
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package debug
+
+import (
+	"os"
+	"time"
+)
+
+// fileCreated returns the modification time of the file, as the creation
+// time is not available on this platform.
+func fileCreated(path string, info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,17 @@
+package debug
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileCreated returns the creation time of the file at path, or its
+// modification time if it is unknown.
+func fileCreated(path string, info os.FileInfo) time.Time {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds())
+}
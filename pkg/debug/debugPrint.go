@@ -1,13 +1,18 @@
-// Package debug provides comprehensive debugging utilities including conditional logging,
+// Package debug provides comprehensive debugging utilities including leveled logging,
 // stacktrace output, and self-destruct functionality for development and testing.
 //
 // The package supports multiple debugging modes:
+//   - Leveled logging: Trace, Debug, Info, Warn and Error with key-value fields
 //   - Debug mode: Enables debug output with optional custom titles
 //   - Stacktrace mode: Includes filtered stack traces in debug output
-//   - Suicide mode: Allows processes to self-terminate after a timeout
+//   - Suicide mode: Allows processes to shut down gracefully after a timeout
 //
-// Configuration can be done via command-line flags or environment variables:
-//   - -debug or DEBUG=true: Enable debug output
+// Configuration can be done via environment variables, which are read at start-up
+// by FromEnv, or command-line flags once RegisterFlags has been called:
+//   - -debug or DEBUG=true: Enable debug output (minimum level "debug")
+//   - -loglevel or DEBUG_LEVEL=<level>: Set the minimum level (trace, debug, info, warn, error)
+//   - -logformat or DEBUG_FORMAT=<format>: Select the output format (text, json, logfmt)
+//   - DEBUG_FILE=<path>: Also write to a size/age rotated log file (see OpenRotatingFile)
 //   - -stacktrace or STACKTRACE=true: Enable stacktrace output
 //   - -suicide or SUICIDE=true: Enable suicide mode
 //
@@ -16,16 +21,19 @@
 //	debug.SetDebug(true)
 //	debug.SetTitle("MYAPP")
 //	debug.Print("This is a debug message")
-//	debug.Suicide(30) // Self-destruct after 30 seconds if suicide mode enabled
+//	debug.Warn("reconnecting", "server", addr, "attempt", 3)
+//
+//	log := debug.New("client").With("server", addr)
+//	log.Info("connected")
+//
+//	debug.OnShutdown(srv.Shutdown)
+//	debug.Suicide(30) // Shut down after 30 seconds if suicide mode enabled
 package debug
 
 import (
-	"flag"
 	"fmt"
-	"log"
 	"os"
-	runDebug "runtime/debug"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -33,41 +41,39 @@ import (
 const defaultTitle = ""
 
 var (
+	// mu guards the package configuration below against concurrent Set calls.
+	mu sync.RWMutex
 	// enableDebug indicates if debug mode is enabled.
 	enableDebug bool = false
 	// enableStacktrace indicates if stacktrace output is enabled.
 	enableStacktrace bool = false
 	// enableSuicide indicates if suicide mode is enabled.
 	enableSuicide bool = false
+	// minLevel is the minimum level set through SetLevel, -loglevel or DEBUG_LEVEL.
+	minLevel Level = LevelInfo
+	// levelSet indicates that minLevel was set explicitly and overrides the debug flag.
+	levelSet bool = false
 	// Title is the current debug title prefix.
+	// Prefer New for scoped titles; Title is shared by the whole process.
 	Title string = defaultTitle
 )
 
-// init registers debug, stacktrace, and suicide flags.
+// init applies the environment configuration. Flags are not registered
+// automatically; see RegisterFlags and the autoflags package.
 func init() {
-	// register debug flag
-	if flag.Lookup("debug") == nil {
-		flag.BoolVar(&enableDebug, "debug", flag.Lookup("debug") != nil || os.Getenv("DEBUG") == "true", "Enable Debug Mode")
-	}
-	if flag.Lookup("stacktrace") == nil {
-		flag.BoolVar(&enableStacktrace, "stacktrace", flag.Lookup("stacktrace") != nil || os.Getenv("STACKTRACE") == "true", "Enable Stacktrace")
-	}
-	if flag.Lookup("suicide") == nil {
-		flag.BoolVar(&enableSuicide, "suicide", flag.Lookup("suicide") != nil || os.Getenv("SUICIDE") == "true", "Enable Suicide Mode")
+	if err := FromEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
 }
 
 // Suicide enables a self-destruct mechanism that will terminate the process after the specified
 // timeout in seconds, but only if suicide mode is enabled via flag or environment variable.
 // This is primarily used for testing and development to prevent runaway processes.
-// The function is non-blocking and starts a goroutine to handle the timeout.
+// The function is non-blocking. When the timeout expires, RootContext is cancelled and the
+// hooks registered with OnShutdown run before the process exits with ExitTimeout.
 func Suicide(timeout int) {
-	if enableSuicide {
-		go func() {
-			time.Sleep(time.Duration(timeout) * time.Second)
-			log.Printf("[TIMEOUT] Exiting after %d seconds (self-destruct)", timeout)
-			os.Exit(0)
-		}()
+	if getSuicide() {
+		StartWatchdog(time.Duration(timeout) * time.Second)
 	}
 }
 
@@ -75,31 +81,13 @@ func Suicide(timeout int) {
 // but only if debug mode is enabled. This is a convenience wrapper around Print that
 // automatically adds a newline. The message is prefixed with [DEBUG] and optional title.
 func Println(message ...any) {
-	Print(message)
-	fmt.Print("\n")
+	Print(message...)
 }
 
 // Print outputs the given message to standard output if debug mode is enabled.
-// Messages are prefixed with [DEBUG] and optionally include a custom title if set.
-// If stacktrace mode is also enabled, a filtered stack trace is included that
-// excludes internal debug package frames for cleaner output.
+// Messages are logged at LevelDebug, prefixed with [DEBUG] and the caller's file:line,
+// and optionally include a custom title if set. If stacktrace mode is also enabled,
+// the stack of the caller is included, starting at the first frame outside this package.
 func Print(message ...any) {
-	if enableDebug {
-		if Title == defaultTitle {
-			log.Print("[DEBUG]", message)
-		} else {
-			log.Print("[DEBUG] {"+Title+"}", message)
-		}
-	}
-	if enableStacktrace && enableDebug {
-		stack := runDebug.Stack()
-		lines := strings.Split(string(stack), "\n")
-		var newLines []string
-		for i := 0; i < len(lines); i++ {
-			if !strings.Contains(lines[i], "github.com/Merith-TK/utils/pkg/debug") && !strings.Contains(lines[i], "runtime/debug") {
-				newLines = append(newLines, lines[i])
-			}
-		}
-		fmt.Print(strings.Join(newLines, "\n"))
-	}
+	std().Print(message...)
 }
@@ -0,0 +1,160 @@
+// Package debughttp provides an opt-in HTTP listener for inspecting a running
+// program: pprof profiles, goroutine dumps, the current log level of pkg/debug
+// (changeable at runtime), build information and expvar counters.
+//
+// It lives in its own package so that programs which do not import it carry
+// no HTTP or expvar code. Note that importing expvar and net/http/pprof
+// registers /debug/vars and /debug/pprof/ on http.DefaultServeMux; programs
+// that serve DefaultServeMux publicly should use their own ServeMux.
+//
+// Endpoints:
+//   - /debug/pprof/            Index of available profiles
+//   - /debug/pprof/cmdline     Command line of the program
+//   - /debug/pprof/profile     CPU profile (?seconds=30)
+//   - /debug/pprof/symbol      Symbol lookup for pprof
+//   - /debug/pprof/trace       Execution trace (?seconds=1)
+//   - /debug/pprof/<name>      Named profile such as heap, allocs, goroutine (?debug=1)
+//   - /debug/goroutines        Full goroutine dump as text
+//   - /debug/level             GET the minimum log level, PUT a new one
+//   - /debug/buildinfo         Runtime and module build information
+//   - /debug/vars              expvar counters, including records per level
+//
+// Example usage:
+//
+//	srv, err := debughttp.Start("") // localhost:6060
+//	if err != nil {
+//		debug.Error("debug listener failed", "err", err)
+//	}
+package debughttp
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runDebug "runtime/debug"
+	runPprof "runtime/pprof"
+	"strings"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+)
+
+// DefaultAddr is the address used when Start is called with an empty address.
+const DefaultAddr = "localhost:6060"
+
+func init() {
+	expvar.Publish("debug.records", expvar.Func(func() any { return debug.Counts() }))
+	expvar.Publish("debug.level", expvar.Func(func() any { return debug.GetLevel().String() }))
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+}
+
+// Handler returns a handler serving all debug endpoints under /debug/.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", serveGoroutines)
+	mux.HandleFunc("/debug/level", serveLevel)
+	mux.HandleFunc("/debug/buildinfo", serveBuildInfo)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// Start listens on addr and serves Handler in the background. An empty addr
+// means DefaultAddr, and an address without a host such as ":6060" is bound
+// to localhost only; give an explicit host like "0.0.0.0:6060" to listen on
+// all interfaces. The server is shut down by the pkg/debug watchdog.
+func Start(addr string) (*http.Server, error) {
+	addr, err := resolveAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: Handler()}
+	debug.OnShutdown(srv.Shutdown)
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			debug.Error("debug listener stopped", "addr", srv.Addr, "err", err)
+		}
+	}()
+	debug.Info("debug listener started", "addr", "http://"+srv.Addr+"/debug/pprof/")
+	return srv, nil
+}
+
+// resolveAddr applies the localhost default to addr.
+func resolveAddr(addr string) (string, error) {
+	if addr == "" {
+		return DefaultAddr, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("debughttp: invalid address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// serveGoroutines writes the stacks of all goroutines.
+func serveGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	runPprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// serveLevel returns the minimum log level on GET and changes it on PUT.
+// The new level is taken from the "level" query parameter or the request body.
+func serveLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		value := r.URL.Query().Get("level")
+		if value == "" {
+			body, err := io.ReadAll(io.LimitReader(r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			value = string(body)
+		}
+		level, err := debug.ParseLevel(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		debug.SetLevel(level)
+		debug.Info("log level changed", "level", level, "remote", r.RemoteAddr)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, strings.ToLower(debug.GetLevel().String())+"\n")
+}
+
+// serveBuildInfo writes the same information as the sys-info command.
+func serveBuildInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "Operating System:", runtime.GOOS)
+	fmt.Fprintln(w, "Architecture:", runtime.GOARCH)
+	fmt.Fprintln(w, "Number of CPUs:", runtime.NumCPU())
+	fmt.Fprintln(w, "Compiler:", runtime.Compiler)
+	fmt.Fprintln(w, "Go Version:", runtime.Version())
+	fmt.Fprintln(w, "Build Target:", runtime.GOOS+"/"+runtime.GOARCH)
+	fmt.Fprintln(w, "")
+	if info, ok := runDebug.ReadBuildInfo(); ok {
+		fmt.Fprint(w, info)
+	} else {
+		fmt.Fprintln(w, "Build Info: unavailable")
+	}
+}
@@ -0,0 +1,146 @@
+package debughttp
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+	"github.com/Merith-TK/utils/pkg/debug/debugtest"
+)
+
+func TestServeLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		want   string // response body prefix
+		level  debug.Level
+	}{
+		{"get", http.MethodGet, "/debug/level", "", http.StatusOK, "warn\n", debug.LevelWarn},
+		{"head", http.MethodHead, "/debug/level", "", http.StatusOK, "", debug.LevelWarn},
+		{"put query", http.MethodPut, "/debug/level?level=debug", "", http.StatusOK, "debug\n", debug.LevelDebug},
+		{"put body", http.MethodPut, "/debug/level", "ERROR\n", http.StatusOK, "error\n", debug.LevelError},
+		{"bad level", http.MethodPut, "/debug/level?level=loud", "", http.StatusBadRequest, "", debug.LevelWarn},
+		{"empty level", http.MethodPut, "/debug/level", "", http.StatusBadRequest, "", debug.LevelWarn},
+		{"post", http.MethodPost, "/debug/level", "debug", http.StatusMethodNotAllowed, "method not allowed\n", debug.LevelWarn},
+		{"delete", http.MethodDelete, "/debug/level", "", http.StatusMethodNotAllowed, "method not allowed\n", debug.LevelWarn},
+	}
+	handler := Handler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := debugtest.CaptureAt(t, debug.LevelWarn)
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.want != "" && w.Body.String() != tt.want {
+				t.Errorf("body %q, want %q", w.Body, tt.want)
+			}
+			if tt.status == http.StatusMethodNotAllowed && w.Header().Get("Allow") != "GET, PUT" {
+				t.Errorf("Allow %q, want GET, PUT", w.Header().Get("Allow"))
+			}
+			if got := debug.GetLevel(); got != tt.level {
+				t.Errorf("level %v, want %v", got, tt.level)
+			}
+			changed := tt.method == http.MethodPut && tt.status == http.StatusOK
+			if changed && tt.level <= debug.LevelInfo {
+				rec.Expect(debugtest.Msg("log level changed"), debugtest.Field("level", tt.level))
+			}
+			if !changed {
+				rec.ExpectNone(debugtest.Msg("log level changed"))
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	handler := Handler()
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/debug/pprof/", "goroutine"},
+		{"/debug/pprof/cmdline", "debughttp.test"},
+		{"/debug/pprof/heap?debug=1", "heap profile"},
+		{"/debug/goroutines", "goroutine "},
+		{"/debug/buildinfo", "Go Version:"},
+		{"/debug/vars", `"debug.level"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("status %d, body without %q:\n%.300s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+// TestOptIn checks that the level and goroutine endpoints are only served by
+// Handler and Start, never by http.DefaultServeMux.
+func TestOptIn(t *testing.T) {
+	for _, target := range []string{"/debug/level", "/debug/goroutines", "/debug/buildinfo"} {
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, target+"?level=trace", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("DefaultServeMux serves %s with status %d", target, w.Code)
+		}
+	}
+}
+
+func TestResolveAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+		err  bool
+	}{
+		{"", DefaultAddr, false},
+		{":6061", "localhost:6061", false},
+		{"0.0.0.0:6060", "0.0.0.0:6060", false},
+		{"[::1]:6060", "[::1]:6060", false},
+		{"localhost", "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolveAddr(tt.addr)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("resolveAddr(%q) = %q, %v; want %q, error %v", tt.addr, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	rec := debugtest.CaptureAt(t, debug.LevelInfo)
+	srv, err := Start("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	host, _, _ := net.SplitHostPort(srv.Addr)
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		t.Errorf("listening on %s, want a loopback address", srv.Addr)
+	}
+	rec.Expect(debugtest.Msg("debug listener started"), debugtest.HasField("addr"))
+
+	resp, err := http.Get("http://" + srv.Addr + "/debug/level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "info\n" {
+		t.Errorf("GET /debug/level = %q, want info", body)
+	}
+	if _, err := Start("bad address"); err == nil {
+		t.Error("Start with an invalid address did not fail")
+	}
+}
@@ -0,0 +1,220 @@
+// Package debugtest captures the output of pkg/debug in tests, so code that
+// logs through debug.Print, debug.Info and friends can be checked for the
+// records it writes instead of by reading stdout.
+//
+// Capture replaces the sinks of pkg/debug for the duration of a test. The
+// debug configuration is process-wide, so tests that capture output must not
+// run in parallel with each other.
+//
+// Example usage:
+//
+//	func TestConnect(t *testing.T) {
+//		rec := debugtest.CaptureAt(t, debug.LevelDebug)
+//		connect("localhost:8080")
+//		rec.Expect(debugtest.Level(debug.LevelWarn), debugtest.Msg("reconnecting"),
+//			debugtest.Field("server", "localhost:8080"))
+//		rec.ExpectNone(debugtest.Level(debug.LevelError))
+//	}
+package debugtest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+)
+
+// Recorder is a debug.Sink that keeps every record written during a test.
+type Recorder struct {
+	t       testing.TB
+	mu      sync.Mutex
+	records []debug.Record
+}
+
+// Capture routes all pkg/debug output to a new Recorder until the test ends,
+// when the previous sinks are restored. Records below the current minimum
+// level are still filtered out; use CaptureAt to change it.
+func Capture(t testing.TB) *Recorder {
+	t.Helper()
+	r := &Recorder{t: t}
+	prev := debug.Sinks()
+	debug.SetSinks(r)
+	t.Cleanup(func() { debug.SetSinks(prev...) })
+	return r
+}
+
+// CaptureAt is like Capture but also sets the minimum level for the
+// duration of the test, e.g. debug.LevelDebug to see debug.Print output.
+// Afterwards the level is restored as it was, following debug mode again
+// if it was not set explicitly.
+func CaptureAt(t testing.TB, level debug.Level) *Recorder {
+	t.Helper()
+	prev, set := debug.LevelOverride()
+	debug.SetLevel(level)
+	t.Cleanup(func() {
+		if set {
+			debug.SetLevel(prev)
+		} else {
+			debug.ClearLevel()
+		}
+	})
+	return Capture(t)
+}
+
+// Write implements debug.Sink.
+func (r *Recorder) Write(rec *debug.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, *rec)
+	return nil
+}
+
+// Records returns a copy of the records captured so far.
+func (r *Recorder) Records() []debug.Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]debug.Record(nil), r.records...)
+}
+
+// Reset discards the records captured so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
+
+// Find returns the captured records that satisfy all matchers.
+func (r *Recorder) Find(matchers ...Matcher) []debug.Record {
+	var found []debug.Record
+	for _, rec := range r.Records() {
+		if matchAll(&rec, matchers) {
+			found = append(found, rec)
+		}
+	}
+	return found
+}
+
+// Expect reports a test error if no captured record satisfies all matchers,
+// and returns the first record that does.
+func (r *Recorder) Expect(matchers ...Matcher) debug.Record {
+	r.t.Helper()
+	found := r.Find(matchers...)
+	if len(found) == 0 {
+		r.t.Errorf("debugtest: no record matching %s\ncaptured:\n%s", describe(matchers), r)
+		return debug.Record{}
+	}
+	return found[0]
+}
+
+// ExpectNone reports a test error for every captured record that satisfies all matchers.
+func (r *Recorder) ExpectNone(matchers ...Matcher) {
+	r.t.Helper()
+	for _, rec := range r.Find(matchers...) {
+		r.t.Errorf("debugtest: unexpected record matching %s: %s", describe(matchers), encode(&rec))
+	}
+}
+
+// ExpectCount reports a test error unless exactly n captured records satisfy all matchers.
+func (r *Recorder) ExpectCount(n int, matchers ...Matcher) {
+	r.t.Helper()
+	if found := r.Find(matchers...); len(found) != n {
+		r.t.Errorf("debugtest: got %d records matching %s, want %d\ncaptured:\n%s", len(found), describe(matchers), n, r)
+	}
+}
+
+// String returns the captured records in the text format, one per line.
+func (r *Recorder) String() string {
+	var b strings.Builder
+	for _, rec := range r.Records() {
+		b.WriteString(encode(&rec))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// encode formats a record with the text encoder, without the trailing newline.
+func encode(rec *debug.Record) string {
+	var buf bytes.Buffer
+	debug.TextEncoder{}.Encode(&buf, rec)
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// Matcher selects records in Find, Expect, ExpectNone and ExpectCount.
+type Matcher struct {
+	desc  string
+	match func(rec *debug.Record) bool
+}
+
+// String describes the matcher in failure messages.
+func (m Matcher) String() string {
+	return m.desc
+}
+
+// Level matches records written at exactly the given level.
+func Level(level debug.Level) Matcher {
+	return Matcher{"level=" + level.String(), func(rec *debug.Record) bool { return rec.Level == level }}
+}
+
+// Title matches records whose title equals title.
+func Title(title string) Matcher {
+	return Matcher{fmt.Sprintf("title=%q", title), func(rec *debug.Record) bool { return rec.Title == title }}
+}
+
+// Msg matches records whose message contains substr.
+func Msg(substr string) Matcher {
+	return Matcher{fmt.Sprintf("msg~%q", substr), func(rec *debug.Record) bool { return strings.Contains(rec.Msg, substr) }}
+}
+
+// HasField matches records that have a field with the given key.
+func HasField(key string) Matcher {
+	return Matcher{"has " + key, func(rec *debug.Record) bool {
+		_, ok := fieldValue(rec, key)
+		return ok
+	}}
+}
+
+// Field matches records that have a field with the given key and value.
+// Values are compared by their fmt.Sprint form, so Field("port", 8080) and
+// Field("port", "8080") are equivalent and errors compare by message.
+func Field(key string, value any) Matcher {
+	want := fmt.Sprint(value)
+	return Matcher{fmt.Sprintf("%s=%v", key, want), func(rec *debug.Record) bool {
+		got, ok := fieldValue(rec, key)
+		return ok && fmt.Sprint(got) == want
+	}}
+}
+
+// fieldValue returns the value of the first field with the given key.
+func fieldValue(rec *debug.Record, key string) (any, bool) {
+	for _, f := range rec.Fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// matchAll reports whether rec satisfies every matcher.
+func matchAll(rec *debug.Record, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m.match(rec) {
+			return false
+		}
+	}
+	return true
+}
+
+// describe joins the matcher descriptions for failure messages.
+func describe(matchers []Matcher) string {
+	if len(matchers) == 0 {
+		return "{any}"
+	}
+	parts := make([]string, len(matchers))
+	for i, m := range matchers {
+		parts[i] = m.desc
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
@@ -0,0 +1,121 @@
+package debugtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+)
+
+// fakeT records the failures a Recorder reports instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCapture(t *testing.T) {
+	rec := CaptureAt(t, debug.LevelInfo)
+	debug.Debug("hidden")
+	debug.Info("connected", "server", "localhost", "port", 8080)
+	debug.New("db").Warn("slow query", "err", fmt.Errorf("timeout"))
+
+	rec.ExpectCount(2)
+	rec.ExpectNone(Msg("hidden"))
+	got := rec.Expect(Level(debug.LevelInfo), Msg("connect"), Field("port", "8080"), HasField("server"))
+	if got.Msg != "connected" {
+		t.Errorf("Expect returned %q, want the connected record", got.Msg)
+	}
+	rec.Expect(Title("db"), Field("err", "timeout"))
+
+	rec.Reset()
+	rec.ExpectCount(0)
+}
+
+func TestCaptureRestores(t *testing.T) {
+	prevSinks := debug.Sinks()
+	t.Run("capture", func(t *testing.T) {
+		CaptureAt(t, debug.LevelTrace)
+		if debug.GetLevel() != debug.LevelTrace {
+			t.Errorf("level %v during the test, want trace", debug.GetLevel())
+		}
+	})
+	if got := debug.Sinks(); len(got) != len(prevSinks) || len(got) > 0 && got[0] != prevSinks[0] {
+		t.Errorf("sinks %v after the test, want %v", got, prevSinks)
+	}
+}
+
+func TestCaptureAtRestoresLevel(t *testing.T) {
+	prevLevel, prevSet := debug.LevelOverride()
+	prevDebug := debug.GetLevel() == debug.LevelDebug && !prevSet
+	t.Cleanup(func() {
+		debug.SetDebug(prevDebug)
+		if prevSet {
+			debug.SetLevel(prevLevel)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		setup func()
+		after func() // changes debug mode after the capture ends
+		want  debug.Level
+	}{
+		{"follows debug mode", func() { debug.SetDebug(false) }, func() { debug.SetDebug(true) }, debug.LevelDebug},
+		{"stays unset", func() { debug.SetDebug(false) }, func() {}, debug.LevelInfo},
+		{"explicit level", func() { debug.SetLevel(debug.LevelWarn) }, func() {}, debug.LevelWarn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			want, wantSet := debug.LevelOverride()
+			t.Run("capture", func(t *testing.T) {
+				CaptureAt(t, debug.LevelTrace)
+			})
+			if got, set := debug.LevelOverride(); set != wantSet || set && got != want {
+				t.Errorf("level override %v, %v after the test, want %v, %v", got, set, want, wantSet)
+			}
+			tt.after()
+			if got := debug.GetLevel(); got != tt.want {
+				t.Errorf("level %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailures(t *testing.T) {
+	ft := &fakeT{}
+	rec := &Recorder{t: ft}
+	rec.Write(&debug.Record{Level: debug.LevelError, Msg: "boom"})
+
+	tests := []struct {
+		name string
+		run  func()
+		want string // substring of the reported failure, or "" for none
+	}{
+		{"Expect matches", func() { rec.Expect(Msg("boom")) }, ""},
+		{"Expect misses", func() { rec.Expect(Msg("bang"), Level(debug.LevelWarn)) }, `no record matching {msg~"bang" level=WARN}`},
+		{"ExpectNone matches", func() { rec.ExpectNone(Level(debug.LevelError)) }, "unexpected record matching {level=ERROR}"},
+		{"ExpectNone misses", func() { rec.ExpectNone(Msg("bang")) }, ""},
+		{"ExpectCount right", func() { rec.ExpectCount(1) }, ""},
+		{"ExpectCount wrong", func() { rec.ExpectCount(2) }, "got 1 records matching {any}, want 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft.errors = nil
+			tt.run()
+			switch {
+			case tt.want == "" && len(ft.errors) > 0:
+				t.Errorf("unexpected failure: %s", ft.errors[0])
+			case tt.want != "" && (len(ft.errors) != 1 || !strings.Contains(ft.errors[0], tt.want)):
+				t.Errorf("failures %q, want one containing %q", ft.errors, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Field is a single key-value pair attached to a Record.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Record is a single log message as handed to sinks and encoders.
+type Record struct {
+	Time   time.Time
+	Level  Level
+	Title  string
+	Msg    string
+	Fields []Field
+	// Caller is the location of the logging call; it is zero when SetCaller(false).
+	Caller Frame
+	// Stack is set in stacktrace mode, or when a field holds an error from Errorf.
+	Stack []Frame
+}
+
+// Encoder serializes a Record onto a writer. Each call must write one complete
+// record in a single Write so that records from concurrent goroutines do not interleave.
+type Encoder interface {
+	Encode(w io.Writer, r *Record) error
+}
+
+// TextEncoder writes human readable lines in the form
+// "2006/01/02 15:04:05 [LEVEL] dir/file.go:42: {title} message key=value",
+// followed by one indented line pair per stack frame if the record has a stack.
+type TextEncoder struct{}
+
+// JSONEncoder writes one JSON object per line with the keys "time", "level",
+// "title", "caller" and "msg" followed by the record's fields in order and,
+// if present, a "stack" array.
+type JSONEncoder struct{}
+
+// LogfmtEncoder writes logfmt lines such as `time=... level=info msg="started" port=8080`.
+type LogfmtEncoder struct{}
+
+// encoders maps format names accepted by SetFormat to their encoders.
+var encoders = map[string]Encoder{
+	"text":   TextEncoder{},
+	"json":   JSONEncoder{},
+	"logfmt": LogfmtEncoder{},
+}
+
+// EncoderFor returns the encoder for a format name: "text", "json" or "logfmt".
+func EncoderFor(format string) (Encoder, error) {
+	enc, ok := encoders[strings.ToLower(strings.TrimSpace(format))]
+	if !ok {
+		return nil, fmt.Errorf("debug: unknown log format %q", format)
+	}
+	return enc, nil
+}
+
+// Encode implements Encoder.
+func (TextEncoder) Encode(w io.Writer, r *Record) error {
+	var b strings.Builder
+	b.WriteString(r.Time.Format("2006/01/02 15:04:05"))
+	b.WriteString(" [" + r.Level.String() + "]")
+	if caller := r.Caller.Short(); caller != "" {
+		b.WriteString(" " + caller + ":")
+	}
+	if r.Title != defaultTitle {
+		b.WriteString(" {" + r.Title + "}")
+	}
+	if r.Msg != "" {
+		b.WriteString(" " + r.Msg)
+	}
+	for _, f := range r.Fields {
+		b.WriteString(" " + f.Key + "=" + quoteValue(formatValue(f.Value)))
+	}
+	b.WriteString("\n")
+	for _, f := range r.Stack {
+		b.WriteString("\t" + f.Function + "\n\t\t" + f.File + ":" + strconv.Itoa(f.Line) + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Encode implements Encoder. A field whose key is already used by the record
+// or an earlier field is written as "fields.<key>".
+func (JSONEncoder) Encode(w io.Writer, r *Record) error {
+	var b bytes.Buffer
+	taken := map[string]bool{}
+	write := func(key string, value any) error {
+		if taken[key] {
+			key = "fields." + key
+		}
+		taken[key] = true
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if b.Len() == 0 {
+			b.WriteByte('{')
+		} else {
+			b.WriteByte(',')
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+		return nil
+	}
+	write("time", r.Time.Format(time.RFC3339Nano))
+	write("level", strings.ToLower(r.Level.String()))
+	if r.Title != defaultTitle {
+		write("title", r.Title)
+	}
+	if caller := r.Caller.Short(); caller != "" {
+		write("caller", caller)
+	}
+	write("msg", r.Msg)
+	for _, f := range r.Fields {
+		if err := write(f.Key, jsonValue(f.Value)); err != nil {
+			return err
+		}
+	}
+	if len(r.Stack) > 0 {
+		stack := make([]string, len(r.Stack))
+		for i, f := range r.Stack {
+			stack[i] = f.String()
+		}
+		write("stack", stack)
+	}
+	b.WriteString("}\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Encode implements Encoder.
+func (LogfmtEncoder) Encode(w io.Writer, r *Record) error {
+	var b strings.Builder
+	b.WriteString("time=" + r.Time.Format(time.RFC3339))
+	b.WriteString(" level=" + strings.ToLower(r.Level.String()))
+	if caller := r.Caller.Short(); caller != "" {
+		b.WriteString(" caller=" + logfmtValue(caller))
+	}
+	if r.Title != defaultTitle {
+		b.WriteString(" title=" + logfmtValue(r.Title))
+	}
+	b.WriteString(" msg=" + logfmtValue(r.Msg))
+	for _, f := range r.Fields {
+		b.WriteString(" " + logfmtKey(f.Key) + "=" + logfmtValue(formatValue(f.Value)))
+	}
+	if len(r.Stack) > 0 {
+		stack := make([]string, len(r.Stack))
+		for i, f := range r.Stack {
+			stack[i] = f.String()
+		}
+		b.WriteString(" stack=" + logfmtValue(strings.Join(stack, "; ")))
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatValue renders a field value as text, preferring the error message for errors.
+func formatValue(value any) string {
+	if err, ok := value.(error); ok && err != nil {
+		return err.Error()
+	}
+	return fmt.Sprint(value)
+}
+
+// jsonValue returns a value that encodes sensibly with encoding/json.
+// Errors and values that json cannot encode are converted to strings.
+func jsonValue(value any) any {
+	switch v := value.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return value
+}
+
+// quoteValue quotes a field value if it would otherwise be ambiguous in text output.
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// logfmtKey replaces characters that are not allowed in logfmt keys with underscores.
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue quotes a value when it is empty or contains spaces, quotes,
+// equals signs or non-printable characters.
+func logfmtValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
+
+// Formats returns the names of the available log formats in sorted order.
+func Formats() []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
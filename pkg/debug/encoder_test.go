@@ -0,0 +1,108 @@
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncoders(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	caller := Frame{Function: "main.run", File: "/src/app/main.go", Line: 42}
+	tests := []struct {
+		name   string
+		record Record
+		text   string
+		json   string
+		logfmt string
+	}{
+		{
+			name:   "plain",
+			record: Record{Time: at, Level: LevelInfo, Msg: "started", Fields: []Field{{"port", 8080}}},
+			text:   "2024/05/06 07:08:09 [INFO] started port=8080\n",
+			json:   `{"time":"2024-05-06T07:08:09Z","level":"info","msg":"started","port":8080}` + "\n",
+			logfmt: "time=2024-05-06T07:08:09Z level=info msg=started port=8080\n",
+		},
+		{
+			name:   "title and caller",
+			record: Record{Time: at, Level: LevelWarn, Title: "db", Caller: caller, Msg: "slow"},
+			text:   "2024/05/06 07:08:09 [WARN] app/main.go:42: {db} slow\n",
+			json:   `{"time":"2024-05-06T07:08:09Z","level":"warn","title":"db","caller":"app/main.go:42","msg":"slow"}` + "\n",
+			logfmt: "time=2024-05-06T07:08:09Z level=warn caller=app/main.go:42 title=db msg=slow\n",
+		},
+		{
+			name: "quoting",
+			record: Record{Time: at, Level: LevelError, Msg: `say "hi"`, Fields: []Field{
+				{"path", "C:\\dir with space"}, {"empty", ""}, {"eq", "a=b"}, {"nl", "a\nb"}, {"ctl", "a\x01b"}, {"uni", "héllo"},
+			}},
+			text:   "2024/05/06 07:08:09 [ERROR] say \"hi\" path=\"C:\\\\dir with space\" empty=\"\" eq=\"a=b\" nl=\"a\\nb\" ctl=a\x01b uni=héllo\n",
+			json:   `{"time":"2024-05-06T07:08:09Z","level":"error","msg":"say \"hi\"","path":"C:\\dir with space","empty":"","eq":"a=b","nl":"a\nb","ctl":"a\u0001b","uni":"héllo"}` + "\n",
+			logfmt: "time=2024-05-06T07:08:09Z level=error msg=\"say \\\"hi\\\"\" path=\"C:\\\\dir with space\" empty=\"\" eq=\"a=b\" nl=\"a\\nb\" ctl=\"a\\x01b\" uni=héllo\n",
+		},
+		{
+			name: "keys",
+			record: Record{Time: at, Level: LevelInfo, Msg: "m", Fields: []Field{
+				{"msg", "dup"}, {"a key", 1}, {"a=b", 2}, {`"q"`, 3},
+			}},
+			text:   "2024/05/06 07:08:09 [INFO] m msg=dup a key=1 a=b=2 \"q\"=3\n",
+			json:   `{"time":"2024-05-06T07:08:09Z","level":"info","msg":"m","fields.msg":"dup","a key":1,"a=b":2,"\"q\"":3}` + "\n",
+			logfmt: "time=2024-05-06T07:08:09Z level=info msg=m msg=dup a_key=1 a_b=2 _q_=3\n",
+		},
+		{
+			name: "values",
+			record: Record{Time: at, Level: LevelDebug, Fields: []Field{
+				{"err", errors.New("boom")}, {"d", 1500 * time.Millisecond}, {"nil", nil}, {"list", []int{1, 2}}, {"c", complex(1, 2)},
+			}},
+			text:   "",
+			json:   `{"time":"2024-05-06T07:08:09Z","level":"debug","msg":"","err":"boom","d":"1.5s","nil":null,"list":[1,2],"c":"(1+2i)"}` + "\n",
+			logfmt: "time=2024-05-06T07:08:09Z level=debug msg=\"\" err=boom d=1.5s nil=<nil> list=\"[1 2]\" c=(1+2i)\n",
+		},
+		{
+			name:   "stack",
+			record: Record{Time: at, Level: LevelError, Msg: "failed", Stack: []Frame{caller, {Function: "main.main", File: "/src/app/main.go", Line: 10}}},
+			text:   "2024/05/06 07:08:09 [ERROR] failed\n\tmain.run\n\t\t/src/app/main.go:42\n\tmain.main\n\t\t/src/app/main.go:10\n",
+			json:   `{"time":"2024-05-06T07:08:09Z","level":"error","msg":"failed","stack":["main.run (/src/app/main.go:42)","main.main (/src/app/main.go:10)"]}` + "\n",
+			logfmt: "time=2024-05-06T07:08:09Z level=error msg=failed stack=\"main.run (/src/app/main.go:42); main.main (/src/app/main.go:10)\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, enc := range []struct {
+				name string
+				want string
+			}{{"text", tt.text}, {"json", tt.json}, {"logfmt", tt.logfmt}} {
+				if enc.want == "" {
+					continue
+				}
+				e, err := EncoderFor(enc.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var buf bytes.Buffer
+				if err := e.Encode(&buf, &tt.record); err != nil {
+					t.Fatal(err)
+				}
+				if got := buf.String(); got != enc.want {
+					t.Errorf("%s:\ngot  %q\nwant %q", enc.name, got, enc.want)
+				}
+				if enc.name == "json" && !json.Valid(buf.Bytes()) {
+					t.Errorf("json: invalid output %s", buf.Bytes())
+				}
+			}
+		})
+	}
+}
+
+func TestEncoderFor(t *testing.T) {
+	for _, name := range []string{"text", " JSON ", "Logfmt"} {
+		if _, err := EncoderFor(name); err != nil {
+			t.Errorf("EncoderFor(%q): %v", name, err)
+		}
+	}
+	if _, err := EncoderFor("xml"); err == nil || !strings.Contains(err.Error(), `unknown log format "xml"`) {
+		t.Errorf("EncoderFor(xml): got %v", err)
+	}
+}
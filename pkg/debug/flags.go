@@ -0,0 +1,134 @@
+package debug
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// envFileSink is the sink opened from DEBUG_FILE, kept so that repeated
+// FromEnv calls do not open the file twice.
+var envFileSink Sink
+
+// RegisterFlags registers the -debug, -loglevel, -logformat, -stacktrace,
+// -stackdepth and -suicide flags on fs. Flags that fs already defines are left alone, so a
+// program can define its own -debug flag without a conflict. The defaults are
+// the current settings, which include the environment variables read by FromEnv.
+//
+// The package no longer registers these flags on flag.CommandLine by itself.
+// Programs call RegisterFlags(flag.CommandLine) before flag.Parse, or import
+// the autoflags package for the previous automatic behavior.
+func RegisterFlags(fs *flag.FlagSet) {
+	if fs.Lookup("debug") == nil {
+		fs.Var(boolFlag{GetDebug, SetDebug}, "debug", "Enable Debug Mode")
+	}
+	if fs.Lookup("loglevel") == nil {
+		fs.Var(levelFlag{}, "loglevel", "Minimum log level (trace, debug, info, warn, error)")
+	}
+	if fs.Lookup("logformat") == nil {
+		fs.Func("logformat", "Log output format (text, json, logfmt)", SetFormat)
+	}
+	if fs.Lookup("stacktrace") == nil {
+		fs.Var(boolFlag{GetStacktrace, SetStacktrace}, "stacktrace", "Enable Stacktrace")
+	}
+	if fs.Lookup("stackdepth") == nil {
+		fs.Func("stackdepth", fmt.Sprintf("Number of frames in stack traces (default %d)", DefaultStackDepth), func(value string) error {
+			depth, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			SetStackDepth(depth)
+			return nil
+		})
+	}
+	if fs.Lookup("suicide") == nil {
+		fs.Var(boolFlag{getSuicide, setSuicide}, "suicide", "Enable Suicide Mode")
+	}
+}
+
+// boolFlag is a boolean flag.Value that goes through the locked accessors of
+// a setting, so that parsing flags does not race with concurrent Get calls.
+type boolFlag struct {
+	get func() bool
+	set func(bool)
+}
+
+func (f boolFlag) String() string {
+	if f.get == nil {
+		return "false"
+	}
+	return strconv.FormatBool(f.get())
+}
+
+func (f boolFlag) Set(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	f.set(enabled)
+	return nil
+}
+
+func (boolFlag) IsBoolFlag() bool {
+	return true
+}
+
+// FromEnv configures the package from environment variables. It runs
+// automatically when the package is initialized and may be called again
+// after the environment has changed. Unset variables leave the current
+// setting untouched; invalid values are reported in the returned error.
+//
+//	DEBUG=true         enable debug mode
+//	DEBUG_LEVEL        minimum level (trace, debug, info, warn, error)
+//	DEBUG_FORMAT       output format (text, json, logfmt)
+//	DEBUG_FILE         rotating log file, see OpenRotatingFile
+//	STACKTRACE=true    enable stack traces
+//	STACKTRACE_DEPTH   number of frames in stack traces
+//	DEBUG_CALLER=false disable the file:line annotation
+//	SUICIDE=true       enable suicide mode
+func FromEnv() error {
+	var errs []error
+	if env, ok := os.LookupEnv("DEBUG"); ok {
+		SetDebug(env == "true")
+	}
+	if env := os.Getenv("DEBUG_LEVEL"); env != "" {
+		if level, err := ParseLevel(env); err != nil {
+			errs = append(errs, err)
+		} else {
+			SetLevel(level)
+		}
+	}
+	if env := os.Getenv("DEBUG_FORMAT"); env != "" {
+		if err := SetFormat(env); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if env, ok := os.LookupEnv("STACKTRACE"); ok {
+		SetStacktrace(env == "true")
+	}
+	if env := os.Getenv("STACKTRACE_DEPTH"); env != "" {
+		if depth, err := strconv.Atoi(env); err != nil {
+			errs = append(errs, fmt.Errorf("debug: invalid STACKTRACE_DEPTH: %w", err))
+		} else {
+			SetStackDepth(depth)
+		}
+	}
+	if env, ok := os.LookupEnv("DEBUG_CALLER"); ok {
+		SetCaller(env != "false")
+	}
+	if env, ok := os.LookupEnv("SUICIDE"); ok {
+		setSuicide(env == "true")
+	}
+	if envFileSink == nil {
+		sink, err := fileSinkFromEnv()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("debug: cannot open DEBUG_FILE: %w", err))
+		} else if sink != nil {
+			envFileSink = sink
+			AddSink(sink)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,44 @@
+package debug
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	prevDebug, prevStack, prevSuicide := enableDebug, GetStacktrace(), getSuicide()
+	t.Cleanup(func() {
+		SetDebug(prevDebug)
+		SetStacktrace(prevStack)
+		setSuicide(prevSuicide)
+	})
+	SetDebug(false)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs)
+	// The flags are parsed while another goroutine reads the settings, as a
+	// logging goroutine would; the race detector checks the locking.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			GetDebug()
+			GetStacktrace()
+			getSuicide()
+		}
+	}()
+	err := fs.Parse([]string{"-debug", "-stacktrace=true", "-suicide"})
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !GetDebug() || !GetStacktrace() || !getSuicide() {
+		t.Errorf("debug %v, stacktrace %v, suicide %v after parsing; want all true", GetDebug(), GetStacktrace(), getSuicide())
+	}
+	if err := fs.Parse([]string{"-debug=false"}); err != nil {
+		t.Fatal(err)
+	}
+	if GetDebug() {
+		t.Error("debug still enabled after -debug=false")
+	}
+}
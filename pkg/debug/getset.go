@@ -3,42 +3,115 @@ package debug
 // SetTitle sets the title of the debug message.
 // Note: this is set globally and will affect all debug messages.
 // It is recommended to use this function at the start of a function followed by defer debug.ResetTitle().
+//
+// Deprecated: concurrent goroutines overwrite each other's title. Use New to
+// create a Logger that carries its own title instead.
 func SetTitle(title string) {
+	mu.Lock()
+	defer mu.Unlock()
 	Title = title
 }
 
-// GetTitle returns the currently set debug message title prefix.
+// GetTitle returns the currently set debug message title prefix.
 // Returns an empty string if no custom title has been set.
 func GetTitle() string {
+	mu.RLock()
+	defer mu.RUnlock()
 	return Title
 }
 
-// ResetTitle resets the debug message title prefix to the default empty value.
+// ResetTitle resets the debug message title prefix to the default empty value.
 // This should be called to clean up after using SetTitle, typically with defer.
+//
+// Deprecated: use New to create a Logger that carries its own title instead.
 func ResetTitle() {
-	Title = defaultTitle
+	SetTitle(defaultTitle)
 }
 
-// SetDebug programmatically enables or disables debug mode, overriding
+// SetDebug programmatically enables or disables debug mode, overriding
 // any command-line flag or environment variable settings.
+// Enabling debug mode sets the minimum level to LevelDebug, disabling it
+// restores the default of LevelInfo.
 func SetDebug(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
 	enableDebug = enabled
+	levelSet = false
 }
 
-// GetDebug returns true if debug mode is currently enabled,
+// GetDebug returns true if debug mode is currently enabled,
 // either through flags, environment variables, or SetDebug calls.
+// It is also true when the minimum level is LevelDebug or LevelTrace.
 func GetDebug() bool {
-	return enableDebug
+	return Enabled(LevelDebug)
+}
+
+// SetLevel sets the minimum level of messages that are written,
+// overriding the -debug flag and DEBUG environment variable.
+func SetLevel(level Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	minLevel = level
+	levelSet = true
+}
+
+// GetLevel returns the current minimum level. Unless set explicitly it is
+// LevelDebug when debug mode is enabled and LevelInfo otherwise.
+func GetLevel() Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	if levelSet {
+		return minLevel
+	}
+	if enableDebug {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// LevelOverride returns the minimum level set through SetLevel, -loglevel or
+// DEBUG_LEVEL, and whether one is set. Without one the level follows debug mode.
+func LevelOverride() (Level, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return minLevel, levelSet
+}
+
+// ClearLevel removes the minimum level set through SetLevel, -loglevel or
+// DEBUG_LEVEL, so that it follows debug mode again.
+func ClearLevel() {
+	mu.Lock()
+	defer mu.Unlock()
+	minLevel = LevelInfo
+	levelSet = false
 }
 
 // SetStacktrace programmatically enables or disables stacktrace output in debug messages,
 // overriding any command-line flag or environment variable settings.
 func SetStacktrace(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
 	enableStacktrace = enabled
 }
 
 // GetStacktrace returns true if stacktrace output is currently enabled
 // for debug messages, either through flags, environment variables, or SetStacktrace calls.
 func GetStacktrace() bool {
+	mu.RLock()
+	defer mu.RUnlock()
 	return enableStacktrace
 }
+
+// setSuicide enables or disables suicide mode, see Suicide.
+func setSuicide(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	enableSuicide = enabled
+}
+
+// getSuicide reports whether suicide mode is enabled.
+func getSuicide() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enableSuicide
+}
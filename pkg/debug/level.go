@@ -0,0 +1,76 @@
+package debug
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of a log message. Messages below the configured
+// minimum level are discarded.
+type Level int
+
+const (
+	// LevelTrace is for very verbose diagnostics such as per-item loop output.
+	LevelTrace Level = iota
+	// LevelDebug is the level used by Print and Println.
+	LevelDebug
+	// LevelInfo is for normal operational messages. It is the default minimum level.
+	LevelInfo
+	// LevelWarn is for recoverable problems such as reconnects or retries.
+	LevelWarn
+	// LevelError is for failures that the caller could not recover from.
+	LevelError
+)
+
+// String returns the upper-case name of the level as it appears in output.
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// ParseLevel converts a level name such as "debug" or "WARN" into a Level.
+// The comparison is case-insensitive and "warning" is accepted as an alias for "warn".
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("debug: unknown log level %q", name)
+	}
+}
+
+// levelFlag adapts the package's minimum level to the flag.Value interface.
+type levelFlag struct{}
+
+func (levelFlag) String() string {
+	return GetLevel().String()
+}
+
+func (levelFlag) Set(value string) error {
+	level, err := ParseLevel(value)
+	if err != nil {
+		return err
+	}
+	SetLevel(level)
+	return nil
+}
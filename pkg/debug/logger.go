@@ -0,0 +1,198 @@
+package debug
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Logger writes leveled messages with its own title and key-value fields.
+// A Logger is never modified after creation; With returns a derived copy,
+// so a single Logger can be shared by any number of goroutines.
+// A nil *Logger behaves like the package-level functions.
+type Logger struct {
+	title   string
+	fields  []any
+	sampler *Sampler
+}
+
+// New returns a Logger whose messages are prefixed with the given title.
+// Unlike SetTitle it does not affect any other logger.
+func New(title string) *Logger {
+	return &Logger{title: title}
+}
+
+// With returns a copy of the logger that adds the given key-value pairs
+// to every message, e.g. logger.With("server", addr).
+func (l *Logger) With(keyvals ...any) *Logger {
+	l = l.orStd()
+	fields := make([]any, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{title: l.title, fields: fields, sampler: l.sampler}
+}
+
+// Title returns the title the logger was created with.
+func (l *Logger) Title() string {
+	return l.orStd().title
+}
+
+// Trace logs a message at LevelTrace with optional key-value fields.
+func (l *Logger) Trace(msg string, keyvals ...any) {
+	l.log(LevelTrace, msg, keyvals)
+}
+
+// Debug logs a message at LevelDebug with optional key-value fields.
+func (l *Logger) Debug(msg string, keyvals ...any) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+// Info logs a message at LevelInfo with optional key-value fields.
+func (l *Logger) Info(msg string, keyvals ...any) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+// Warn logs a message at LevelWarn with optional key-value fields.
+func (l *Logger) Warn(msg string, keyvals ...any) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+// Error logs a message at LevelError with optional key-value fields.
+func (l *Logger) Error(msg string, keyvals ...any) {
+	l.log(LevelError, msg, keyvals)
+}
+
+// Print logs the operands like fmt.Sprintln at LevelDebug. In stacktrace
+// mode the record includes the stack of the calling goroutine.
+func (l *Logger) Print(message ...any) {
+	if !Enabled(LevelDebug) {
+		return
+	}
+	l.log(LevelDebug, strings.TrimSuffix(fmt.Sprintln(message...), "\n"), nil)
+}
+
+// orStd returns l, or the package-level logger if l is nil.
+func (l *Logger) orStd() *Logger {
+	if l == nil {
+		return std()
+	}
+	return l
+}
+
+// log merges the logger's fields with keyvals and writes the message,
+// unless the logger's sampler suppresses it.
+func (l *Logger) log(level Level, msg string, keyvals []any) {
+	if !Enabled(level) {
+		return
+	}
+	l = l.orStd()
+	fields := keyvals
+	if len(l.fields) > 0 {
+		fields = make([]any, 0, len(l.fields)+len(keyvals))
+		fields = append(fields, l.fields...)
+		fields = append(fields, keyvals...)
+	}
+	if l.sampler != nil {
+		ok, suppressed := l.sampler.allow(level, l.title, msg)
+		if !ok {
+			return
+		}
+		if suppressed > 0 {
+			fields = append(fields[:len(fields):len(fields)], "suppressed", suppressed)
+		}
+	}
+	logf(level, l.title, msg, fields)
+}
+
+// std returns a logger using the process-wide Title set by SetTitle.
+func std() *Logger {
+	return &Logger{title: GetTitle()}
+}
+
+// Trace logs a message at LevelTrace with optional key-value fields.
+// Fields are given as alternating keys and values, e.g. Trace("block", "x", 1, "y", 2).
+func Trace(msg string, keyvals ...any) {
+	std().log(LevelTrace, msg, keyvals)
+}
+
+// Debug logs a message at LevelDebug with optional key-value fields.
+func Debug(msg string, keyvals ...any) {
+	std().log(LevelDebug, msg, keyvals)
+}
+
+// Info logs a message at LevelInfo with optional key-value fields.
+func Info(msg string, keyvals ...any) {
+	std().log(LevelInfo, msg, keyvals)
+}
+
+// Warn logs a message at LevelWarn with optional key-value fields.
+// Use it for recoverable problems such as a dropped connection that will be retried.
+func Warn(msg string, keyvals ...any) {
+	std().log(LevelWarn, msg, keyvals)
+}
+
+// Error logs a message at LevelError with optional key-value fields.
+// Unlike log.Fatal it does not terminate the process.
+func Error(msg string, keyvals ...any) {
+	std().log(LevelError, msg, keyvals)
+}
+
+// Enabled reports whether messages at the given level are currently written.
+func Enabled(level Level) bool {
+	return level >= GetLevel()
+}
+
+// logf builds a Record from the message and key-value pairs and hands it to the sinks.
+// The caller and stack are captured here, skipping the frames of this package.
+func logf(level Level, title, msg string, keyvals []any) {
+	r := &Record{
+		Time:  time.Now(),
+		Level: level,
+		Title: title,
+		Msg:   msg,
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		key, value := fieldPair(keyvals, i)
+		r.Fields = append(r.Fields, Field{Key: key, Value: value})
+		if err, ok := value.(error); ok && r.Stack == nil {
+			r.Stack = StackOf(err)
+		}
+	}
+	if GetCaller() {
+		r.Caller, _ = caller()
+	}
+	if GetStacktrace() && r.Stack == nil {
+		r.Stack = callers(GetStackDepth())
+	}
+	if level >= LevelTrace && level <= LevelError {
+		recordCounts[level].Add(1)
+	}
+	dispatch(r)
+}
+
+// recordCounts counts the records written per level.
+var recordCounts [LevelError + 1]atomic.Uint64
+
+// Counts returns the number of records written so far per level name,
+// e.g. {"info": 12, "warn": 1}. Filtered records are not counted.
+func Counts() map[string]uint64 {
+	counts := make(map[string]uint64, len(recordCounts))
+	for level := range recordCounts {
+		counts[strings.ToLower(Level(level).String())] = recordCounts[level].Load()
+	}
+	return counts
+}
+
+// fieldPair returns the key and value starting at index i of keyvals.
+// A trailing key without a value is reported under the key "!BADKEY".
+func fieldPair(keyvals []any, i int) (string, any) {
+	if i+1 >= len(keyvals) {
+		return "!BADKEY", keyvals[i]
+	}
+	key, ok := keyvals[i].(string)
+	if !ok {
+		key = fmt.Sprint(keyvals[i])
+	}
+	return key, keyvals[i+1]
+}
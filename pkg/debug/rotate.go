@@ -0,0 +1,342 @@
+package debug
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateOptions configures when a RotatingFile is rotated and how many
+// rotated files are kept. Zero values disable the corresponding limit.
+type RotateOptions struct {
+	// MaxSize is the size in bytes after which the file is rotated.
+	MaxSize int64
+	// MaxAge is how long a file is written to before it is rotated.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep. Older ones are deleted.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// backupTimeFormat is used in the names of rotated files, e.g. "app.20250102-150405.000.log".
+const backupTimeFormat = "20060102-150405.000"
+
+// RotatingFile is an io.WriteCloser that appends to a log file and rotates it
+// by size and age. Rotated files are renamed with a timestamp, optionally
+// gzipped, and pruned to RotateOptions.MaxBackups. It is safe for concurrent use.
+type RotatingFile struct {
+	mu     sync.Mutex
+	path   string
+	opts   RotateOptions
+	file   *os.File // nil after Close or a failed rotation
+	closed bool
+	size   int64
+	opened time.Time
+}
+
+// OpenRotatingFile opens or creates the log file at path for appending.
+// The parent directory is created if needed.
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write implements io.Writer. The file is rotated before the write if it
+// would exceed MaxSize or is older than MaxAge. If a failed rotation left no
+// file open, Write opens it again. When the rotation fails after a file was
+// opened, e.g. while compressing the backup, p is still written and the
+// rotation error returned.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	var rotateErr error
+	if rf.shouldRotate(int64(len(p))) {
+		if rotateErr = rf.rotate(); rf.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Rotate closes the current file, renames it with a timestamp and starts a new one.
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.rotate()
+}
+
+// Close closes the current file. Further writes return os.ErrClosed.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.closed = true
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// Path returns the path of the active log file.
+func (rf *RotatingFile) Path() string {
+	return rf.path
+}
+
+// open opens the active file for appending and records its size.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	rf.opened = time.Now()
+	if rf.size > 0 {
+		rf.opened = fileCreated(rf.path, info)
+	}
+	return nil
+}
+
+// shouldRotate reports whether writing n more bytes requires a rotation first.
+func (rf *RotatingFile) shouldRotate(n int64) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.opts.MaxSize > 0 && rf.size+n > rf.opts.MaxSize {
+		return true
+	}
+	return rf.opts.MaxAge > 0 && time.Since(rf.opened) > rf.opts.MaxAge
+}
+
+// rotate must be called with rf.mu held. If the active file cannot be
+// renamed it is reopened, so that logging continues in it; the new file is
+// opened before the backup is compressed, so a failure there only leaves the
+// backup uncompressed.
+func (rf *RotatingFile) rotate() error {
+	if rf.file != nil {
+		err := rf.file.Close()
+		rf.file = nil
+		if err != nil {
+			return rf.reopen(err)
+		}
+	}
+	backup := rf.backupName(time.Now())
+	if err := os.Rename(rf.path, backup); err != nil && !os.IsNotExist(err) {
+		return rf.reopen(err)
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	if rf.opts.Compress {
+		if err := gzipFile(backup); err != nil {
+			return err
+		}
+	}
+	return rf.prune()
+}
+
+// backupName returns a free name for a file rotated at t. If a backup of
+// the same millisecond exists, the time is advanced until the name is free,
+// so that the names still sort in the order of rotation.
+func (rf *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(rf.path)
+	for {
+		backup := strings.TrimSuffix(rf.path, ext) + "." + t.Format(backupTimeFormat) + ext
+		_, err := os.Lstat(backup)
+		_, gzErr := os.Lstat(backup + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return backup
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// reopen opens the active file again after a rotation failed, and returns
+// err along with any error from opening it.
+func (rf *RotatingFile) reopen(err error) error {
+	if oerr := rf.open(); oerr != nil {
+		return errors.Join(err, oerr)
+	}
+	return err
+}
+
+// prune deletes the oldest rotated files beyond MaxBackups.
+func (rf *RotatingFile) prune() error {
+	if rf.opts.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := rf.backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > rf.opts.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// backups returns the rotated files of rf, oldest first.
+func (rf *RotatingFile) backups() ([]string, error) {
+	ext := filepath.Ext(rf.path)
+	prefix := filepath.Base(strings.TrimSuffix(rf.path, ext)) + "."
+	entries, err := os.ReadDir(filepath.Dir(rf.path))
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix)); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(rf.path), name))
+	}
+	// The timestamp format sorts chronologically.
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// gzipFile compresses path to path+".gz" and removes the original. If it
+// fails, the original is kept and the partial ".gz" file removed.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	in.Close()
+	return os.Remove(path)
+}
+
+// fileSinkFromEnv opens the rotating log file configured by DEBUG_FILE and
+// its companion variables. It returns nil if DEBUG_FILE is not set.
+//
+//	DEBUG_FILE              path of the log file
+//	DEBUG_FILE_FORMAT       text, json or logfmt (default: the -logformat setting)
+//	DEBUG_FILE_MAX_SIZE     rotate after this size, e.g. 512KB, 10MB (default 10MB)
+//	DEBUG_FILE_MAX_AGE      rotate after this duration, e.g. 24h (default: never)
+//	DEBUG_FILE_MAX_BACKUPS  number of rotated files to keep (default 5)
+//	DEBUG_FILE_COMPRESS     gzip rotated files when "true"
+func fileSinkFromEnv() (Sink, error) {
+	path := os.Getenv("DEBUG_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	opts := RotateOptions{
+		MaxSize:    10 << 20,
+		MaxBackups: 5,
+		Compress:   os.Getenv("DEBUG_FILE_COMPRESS") == "true",
+	}
+	if env := os.Getenv("DEBUG_FILE_MAX_SIZE"); env != "" {
+		size, err := ParseSize(env)
+		if err != nil {
+			return nil, err
+		}
+		opts.MaxSize = size
+	}
+	if env := os.Getenv("DEBUG_FILE_MAX_AGE"); env != "" {
+		age, err := time.ParseDuration(env)
+		if err != nil {
+			return nil, fmt.Errorf("debug: invalid DEBUG_FILE_MAX_AGE: %w", err)
+		}
+		opts.MaxAge = age
+	}
+	if env := os.Getenv("DEBUG_FILE_MAX_BACKUPS"); env != "" {
+		n, err := strconv.Atoi(env)
+		if err != nil {
+			return nil, fmt.Errorf("debug: invalid DEBUG_FILE_MAX_BACKUPS: %w", err)
+		}
+		opts.MaxBackups = n
+	}
+	var enc Encoder
+	if env := os.Getenv("DEBUG_FILE_FORMAT"); env != "" {
+		var err error
+		if enc, err = EncoderFor(env); err != nil {
+			return nil, err
+		}
+	}
+	rf, err := OpenRotatingFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(rf, enc), nil
+}
+
+// ParseSize parses a byte size such as "1024", "512KB", "10MB" or "1GB".
+// Units are powers of 1024 and are case-insensitive.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, unit.suffix))
+			mult = unit.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("debug: invalid size %q", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,127 @@
+package debug
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rf, err := OpenRotatingFile(path, RotateOptions{MaxSize: 10, MaxBackups: 1, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "third\n" {
+		t.Errorf("active file %q, %v; want the last line", data, err)
+	}
+	backups, err := rf.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Errorf("backups %v, want one compressed file", backups)
+	}
+}
+
+func TestRotatingFileRecovers(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "app.log")
+	rf, err := OpenRotatingFile(path, RotateOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	if _, err := rf.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new file cannot be created while the directory is missing.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rf.Write([]byte("second\n")); err == nil {
+		t.Fatal("rotating into a missing directory succeeded")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rf.Write([]byte("third\n")); err != nil {
+		t.Fatalf("write after the failed rotation: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "third\n" {
+		t.Errorf("active file %q, %v; want the write after the failure", data, err)
+	}
+
+	rf.Close()
+	if _, err := rf.Write([]byte("closed\n")); err != os.ErrClosed {
+		t.Errorf("write after Close: %v, want os.ErrClosed", err)
+	}
+}
+
+func TestRotatingFileBackupNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := OpenRotatingFile(path, RotateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	// Rotations within the same millisecond must not overwrite each other.
+	for i := 0; i < 5; i++ {
+		if _, err := rf.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := rf.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := rf.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 5 {
+		t.Errorf("%d backups after 5 rotations: %v", len(backups), backups)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A file created now but last written long ago, as if by a process
+	// that was stopped, is not due for rotation.
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileCreated(path, info).Equal(info.ModTime()) {
+		t.Skip("the file system does not record creation times")
+	}
+
+	rf, err := OpenRotatingFile(path, RotateOptions{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	if _, err := rf.Write([]byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := rf.backups(); len(backups) != 0 {
+		t.Errorf("rotated a file created now: %v", backups)
+	}
+}
@@ -0,0 +1,136 @@
+package debug
+
+import (
+	"sync"
+	"time"
+)
+
+// Sampler limits how often each call site of a Logger is written, so that
+// hot loops can keep their diagnostics without flooding the output. A call
+// site is the file:line of the logging call combined with the level.
+//
+// Per window, the first First records of a call site are written, then
+// every Every-th record; the rest are suppressed. The next record written
+// for that site carries a "suppressed" field with the number dropped since
+// the previous one, and Flush reports whatever is still pending.
+//
+// A Sampler is safe for concurrent use and can be shared by many loggers.
+type Sampler struct {
+	first int
+	every int
+	per   time.Duration
+
+	mu    sync.Mutex
+	sites map[sampleKey]*sampleSite
+	order []sampleKey
+}
+
+// sampleKey identifies a call site.
+type sampleKey struct {
+	file  string
+	line  int
+	level Level
+}
+
+// sampleSite is the sampling state of one call site.
+type sampleSite struct {
+	caller     Frame
+	title      string
+	msg        string
+	n          int
+	suppressed int
+	total      uint64
+	start      time.Time
+}
+
+// NewSampler returns a sampler that writes the first records of each call
+// site and then every every-th one. Every below 1 suppresses everything
+// after the first records. If per is positive the counts restart every per,
+// which turns the sampler into a rate limit of first records per interval,
+// e.g. NewSampler(5, 0, time.Second).
+func NewSampler(first, every int, per time.Duration) *Sampler {
+	return &Sampler{
+		first: first,
+		every: every,
+		per:   per,
+		sites: make(map[sampleKey]*sampleSite),
+	}
+}
+
+// WithSampler returns a copy of the logger whose records are sampled by s.
+// Loggers derived with With share the sampler. A nil sampler disables sampling.
+func (l *Logger) WithSampler(s *Sampler) *Logger {
+	l = l.orStd()
+	return &Logger{title: l.title, fields: l.fields, sampler: s}
+}
+
+// allow records one message at the calling site and reports whether it
+// should be written, along with the number suppressed since the last one.
+func (s *Sampler) allow(level Level, title, msg string) (bool, int) {
+	site, _ := caller()
+	key := sampleKey{file: site.File, line: site.Line, level: level}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.sites[key]
+	if !ok {
+		st = &sampleSite{caller: site, start: now}
+		s.sites[key] = st
+		s.order = append(s.order, key)
+	}
+	if s.per > 0 && now.Sub(st.start) >= s.per {
+		st.n = 0
+		st.start = now
+	}
+	st.n++
+	st.title = title
+	st.msg = msg
+	if st.n <= s.first || (s.every > 0 && (st.n-s.first)%s.every == 0) {
+		suppressed := st.suppressed
+		st.suppressed = 0
+		return true, suppressed
+	}
+	st.suppressed++
+	st.total++
+	return false, 0
+}
+
+// Flush writes one summary record for every call site with suppressed
+// records that have not been reported yet. Call it when a hot loop ends.
+func (s *Sampler) Flush() {
+	type pending struct {
+		key   sampleKey
+		site  sampleSite
+		count int
+	}
+	s.mu.Lock()
+	var out []pending
+	for _, key := range s.order {
+		st := s.sites[key]
+		if st.suppressed > 0 {
+			out = append(out, pending{key: key, site: *st, count: st.suppressed})
+			st.suppressed = 0
+		}
+	}
+	s.mu.Unlock()
+
+	for _, p := range out {
+		if !Enabled(p.key.level) {
+			continue
+		}
+		logf(p.key.level, p.site.title, "suppressed similar messages",
+			[]any{"site", p.site.caller.Short(), "last", p.site.msg, "suppressed", p.count})
+	}
+}
+
+// Suppressed returns the total number of records the sampler has dropped.
+func (s *Sampler) Suppressed() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total uint64
+	for _, st := range s.sites {
+		total += st.total
+	}
+	return total
+}
@@ -0,0 +1,32 @@
+package debug_test
+
+import (
+	"testing"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+	"github.com/Merith-TK/utils/pkg/debug/debugtest"
+)
+
+func TestSampler(t *testing.T) {
+	rec := debugtest.CaptureAt(t, debug.LevelInfo)
+	s := debug.NewSampler(2, 3, 0)
+	logger := debug.New("loop").WithSampler(s)
+	for i := 0; i < 9; i++ {
+		logger.Info("tick", "i", i)
+	}
+
+	// The first two, then every third: the 5th and the 8th.
+	rec.ExpectCount(4, debugtest.Msg("tick"))
+	rec.Expect(debugtest.Field("i", 4), debugtest.Field("suppressed", 2))
+	rec.Expect(debugtest.Field("i", 7), debugtest.Field("suppressed", 2))
+	if got := s.Suppressed(); got != 5 {
+		t.Errorf("Suppressed() = %d, want 5", got)
+	}
+
+	s.Flush()
+	rec.Expect(debugtest.Title("loop"), debugtest.Msg("suppressed similar messages"),
+		debugtest.Field("last", "tick"), debugtest.Field("suppressed", 1))
+	rec.Reset()
+	s.Flush()
+	rec.ExpectCount(0)
+}
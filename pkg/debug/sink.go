@@ -0,0 +1,141 @@
+package debug
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// Sink receives every record that passes the minimum level filter.
+type Sink interface {
+	Write(r *Record) error
+}
+
+// writerSink encodes records onto an io.Writer.
+type writerSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc Encoder
+}
+
+// NewWriterSink returns a Sink that encodes records onto w using enc.
+// If enc is nil the format selected by SetFormat, -logformat or DEBUG_FORMAT is used.
+// Writes are serialized, so w does not need to be safe for concurrent use.
+func NewWriterSink(w io.Writer, enc Encoder) Sink {
+	return &writerSink{w: w, enc: enc}
+}
+
+// Write implements Sink.
+func (s *writerSink) Write(r *Record) error {
+	enc := s.enc
+	if enc == nil {
+		enc = defaultEncoder()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return enc.Encode(s.w, r)
+}
+
+var (
+	// sinkMu guards sinks and format.
+	sinkMu sync.RWMutex
+	// sinks receive all records. When empty, records go to log.Writer().
+	sinks []Sink
+	// format is the name of the encoder used by sinks without their own encoder.
+	format = "text"
+)
+
+// SetFormat selects the default output format: "text", "json" or "logfmt".
+// It applies to the default output and to sinks created without an encoder.
+func SetFormat(name string) error {
+	if _, err := EncoderFor(name); err != nil {
+		return err
+	}
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	format = name
+	return nil
+}
+
+// GetFormat returns the name of the default output format.
+func GetFormat() string {
+	sinkMu.RLock()
+	defer sinkMu.RUnlock()
+	return format
+}
+
+// SetSinks replaces all sinks. Calling it without arguments restores the
+// default output, which is the writer of the standard log package.
+func SetSinks(s ...Sink) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sinks = append([]Sink(nil), s...)
+}
+
+// AddSink adds a sink in addition to the existing ones. If no sinks were
+// configured yet, the default output is kept as the first sink.
+func AddSink(s Sink) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	if len(sinks) == 0 {
+		sinks = append(sinks, stdSink)
+	}
+	sinks = append(sinks, s)
+}
+
+// Sinks returns the currently configured sinks. The result is empty while
+// the default output is in use.
+func Sinks() []Sink {
+	sinkMu.RLock()
+	defer sinkMu.RUnlock()
+	return append([]Sink(nil), sinks...)
+}
+
+// SetOutput sends all output to w using the default format,
+// replacing any configured sinks.
+func SetOutput(w io.Writer) {
+	SetSinks(NewWriterSink(w, nil))
+}
+
+// defaultEncoder returns the encoder for the current default format.
+func defaultEncoder() Encoder {
+	enc, err := EncoderFor(GetFormat())
+	if err != nil {
+		return TextEncoder{}
+	}
+	return enc
+}
+
+// stdSink writes to whatever writer the standard log package currently uses.
+var stdSink Sink = logWriterSink{}
+
+// logWriterSink is the default sink. It looks up log.Writer() on every write
+// so that log.SetOutput keeps working for this package too.
+type logWriterSink struct{}
+
+var logWriterMu sync.Mutex
+
+// Write implements Sink.
+func (logWriterSink) Write(r *Record) error {
+	logWriterMu.Lock()
+	defer logWriterMu.Unlock()
+	return defaultEncoder().Encode(log.Writer(), r)
+}
+
+// dispatch hands a record to every sink. Sink errors are reported on
+// stderr since there is nowhere else to log them.
+func dispatch(r *Record) {
+	sinkMu.RLock()
+	targets := sinks
+	sinkMu.RUnlock()
+	if len(targets) == 0 {
+		targets = []Sink{stdSink}
+	}
+	for _, s := range targets {
+		if err := s.Write(r); err != nil {
+			fmt.Fprintf(os.Stderr, "debug: sink error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package debug
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exit codes reported by a Watchdog.
+const (
+	// ExitOK means shutdown was requested and all hooks finished in time.
+	ExitOK = 0
+	// ExitTimeout means the watchdog timer expired, matching timeout(1).
+	ExitTimeout = 124
+	// ExitShutdownFailed means a shutdown hook failed or the grace period ran out.
+	ExitShutdownFailed = 125
+)
+
+// DefaultGrace is the grace period of the package-level watchdog.
+const DefaultGrace = 5 * time.Second
+
+// DefaultSuicideTimeout is the timeout in seconds that long-running
+// programs pass to Suicide.
+const DefaultSuicideTimeout = 60
+
+// Watchdog owns a root context for a program. When its timer expires, or
+// Shutdown is called, it cancels the context, runs the registered shutdown
+// hooks within a grace period and reports an exit code. Only a timer expiry
+// started by Start terminates the process, and only after the hooks ran.
+type Watchdog struct {
+	// Grace is how long shutdown hooks may take in total.
+	Grace time.Duration
+	// Exit is called with the exit code after a timer expiry. It defaults to
+	// os.Exit; tests can replace it to observe the code instead.
+	Exit func(code int)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	hooks  []func(context.Context) error
+	once   sync.Once
+	done   chan struct{}
+	code   int
+}
+
+// NewWatchdog returns a watchdog whose context is derived from parent.
+func NewWatchdog(parent context.Context, grace time.Duration) *Watchdog {
+	ctx, cancel := context.WithCancel(parent)
+	return &Watchdog{
+		Grace:  grace,
+		Exit:   os.Exit,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Context returns the root context, which is cancelled when shutdown begins.
+func (w *Watchdog) Context() context.Context {
+	return w.ctx
+}
+
+// OnShutdown registers a hook that runs during shutdown. Hooks run in reverse
+// order of registration, like deferred calls, and receive a context that
+// expires at the end of the grace period.
+func (w *Watchdog) OnShutdown(hook func(ctx context.Context) error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hooks = append(w.hooks, hook)
+}
+
+// Start arms the watchdog timer. When timeout elapses before the context is
+// cancelled, the watchdog shuts down with ExitTimeout and then calls Exit.
+func (w *Watchdog) Start(timeout time.Duration) {
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			Warn("watchdog timeout, shutting down", "timeout", timeout, "grace", w.Grace)
+			w.Exit(w.Shutdown(ExitTimeout))
+		case <-w.ctx.Done():
+		}
+	}()
+}
+
+// Shutdown cancels the root context, runs the shutdown hooks and returns the
+// resulting exit code: code if all hooks succeeded within the grace period,
+// ExitShutdownFailed otherwise. Only the first call runs the hooks; later
+// calls wait for it to finish and return the same code.
+func (w *Watchdog) Shutdown(code int) int {
+	w.once.Do(func() {
+		w.cancel()
+		w.code = w.runHooks(code)
+		close(w.done)
+	})
+	<-w.done
+	return w.code
+}
+
+// Done returns a channel that is closed once shutdown has completed.
+func (w *Watchdog) Done() <-chan struct{} {
+	return w.done
+}
+
+// runHooks runs the hooks in reverse order, bounded by the grace period.
+func (w *Watchdog) runHooks(code int) int {
+	w.mu.Lock()
+	hooks := append([]func(context.Context) error(nil), w.hooks...)
+	w.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.Grace)
+	defer cancel()
+
+	finished := make(chan bool, 1)
+	go func() {
+		ok := true
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i](ctx); err != nil {
+				Error("shutdown hook failed", "err", err)
+				ok = false
+			}
+		}
+		finished <- ok
+	}()
+
+	select {
+	case ok := <-finished:
+		if !ok {
+			return ExitShutdownFailed
+		}
+		return code
+	case <-ctx.Done():
+		Error("shutdown hooks exceeded grace period", "grace", w.Grace)
+		return ExitShutdownFailed
+	}
+}
+
+// watchdog is the package-level watchdog used by Suicide and the helpers below.
+var watchdog = NewWatchdog(context.Background(), DefaultGrace)
+
+// RootContext returns the context of the package-level watchdog. Pass it to
+// long-running work so that it stops when the program shuts down.
+func RootContext() context.Context {
+	return watchdog.Context()
+}
+
+// OnShutdown registers a hook on the package-level watchdog.
+func OnShutdown(hook func(ctx context.Context) error) {
+	watchdog.OnShutdown(hook)
+}
+
+// StartWatchdog arms the package-level watchdog regardless of suicide mode.
+// After timeout it cancels RootContext, runs the shutdown hooks and exits
+// with ExitTimeout, or ExitShutdownFailed if the hooks did not complete.
+func StartWatchdog(timeout time.Duration) {
+	watchdog.Start(timeout)
+}
+
+// Shutdown shuts the package-level watchdog down without exiting the process
+// and returns the exit code; see (*Watchdog).Shutdown.
+func Shutdown(code int) int {
+	return watchdog.Shutdown(code)
+}
+
+// Done returns a channel that is closed once the package-level watchdog has shut down.
+func Done() <-chan struct{} {
+	return watchdog.Done()
+}
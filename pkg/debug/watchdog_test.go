@@ -0,0 +1,91 @@
+package debug_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Merith-TK/utils/pkg/debug"
+	"github.com/Merith-TK/utils/pkg/debug/debugtest"
+)
+
+func TestWatchdogTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		hook func(ctx context.Context) error
+		want int
+	}{
+		{"hooks succeed", func(ctx context.Context) error { return nil }, debug.ExitTimeout},
+		{"hook fails", func(ctx context.Context) error { return errors.New("boom") }, debug.ExitShutdownFailed},
+		{"hook exceeds grace", func(ctx context.Context) error {
+			time.Sleep(500 * time.Millisecond)
+			return nil
+		}, debug.ExitShutdownFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := debugtest.Capture(t)
+			w := debug.NewWatchdog(context.Background(), 50*time.Millisecond)
+			exited := make(chan int, 1)
+			w.Exit = func(code int) { exited <- code }
+
+			var mu sync.Mutex
+			var order []int
+			for i := 0; i < 2; i++ {
+				w.OnShutdown(func(ctx context.Context) error {
+					if w.Context().Err() == nil {
+						t.Error("hook ran before the root context was cancelled")
+					}
+					mu.Lock()
+					order = append(order, i)
+					mu.Unlock()
+					return nil
+				})
+			}
+			w.OnShutdown(tt.hook)
+			w.Start(10 * time.Millisecond)
+
+			select {
+			case code := <-exited:
+				if code != tt.want {
+					t.Errorf("exit code %d, want %d", code, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the watchdog did not exit")
+			}
+			// A later Shutdown, as at the end of main, reports the same code.
+			if code := w.Shutdown(debug.ExitOK); code != tt.want {
+				t.Errorf("Shutdown after the timeout returned %d, want %d", code, tt.want)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.want == debug.ExitTimeout && !slices.Equal(order, []int{1, 0}) {
+				t.Errorf("hooks ran in order %v, want reverse registration order", order)
+			}
+			rec.Expect(debugtest.Level(debug.LevelWarn), debugtest.Msg("watchdog timeout"))
+		})
+	}
+}
+
+func TestWatchdogShutdown(t *testing.T) {
+	w := debug.NewWatchdog(context.Background(), time.Second)
+	w.Exit = func(code int) { t.Errorf("Exit(%d) called after Shutdown", code) }
+	w.Start(50 * time.Millisecond)
+
+	if code := w.Shutdown(debug.ExitOK); code != debug.ExitOK {
+		t.Errorf("Shutdown returned %d, want %d", code, debug.ExitOK)
+	}
+	select {
+	case <-w.Done():
+	default:
+		t.Error("Done is not closed after Shutdown")
+	}
+	if w.Context().Err() == nil {
+		t.Error("the root context was not cancelled")
+	}
+	// The timer stops with the context, so Exit is never called.
+	time.Sleep(100 * time.Millisecond)
+}